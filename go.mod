@@ -6,8 +6,11 @@ require (
 	github.com/golang-migrate/migrate/v4 v4.19.1
 	github.com/olivere/vite v0.1.0
 	github.com/realclientip/realclientip-go v1.0.0
+	github.com/rivo/uniseg v0.4.7
 	github.com/rs/cors v1.11.1
 	golang.ngrok.com/ngrok v1.13.0
+	golang.org/x/image v0.38.0
+	golang.org/x/text v0.35.0
 	modernc.org/sqlite v1.46.1
 	schneider.vip/problem v1.9.1
 )
@@ -160,7 +163,6 @@ require (
 	golang.org/x/sync v0.20.0 // indirect
 	golang.org/x/telemetry v0.0.0-20260311193753-579e4da9a98c // indirect
 	golang.org/x/term v0.41.0 // indirect
-	golang.org/x/text v0.35.0 // indirect
 	golang.org/x/time v0.12.0 // indirect
 	golang.org/x/tools v0.43.0 // indirect
 	golang.org/x/tools/godoc v0.1.0-deprecated // indirect
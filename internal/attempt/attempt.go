@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/tmaxmax/popthegrid/internal/handler/sessionrand"
 )
 
@@ -60,18 +61,13 @@ func (g *Gamemode) UnmarshalJSON(data []byte) error {
 	return unmarshalEnum(data, g)
 }
 
-func (g Gamemode) Description() string {
+// UsesRand reports whether the gamemode's outcome depends on the session random stream.
+func (g Gamemode) UsesRand() bool {
 	switch g {
-	case GamemodeRandom:
-		return "win more than me."
-	case GamemodeSameSquare:
-		return "destroy faster the same squares."
-	case GamemodePassthrough:
-		return "be faster than me."
-	case GamemodeOddOneOut:
-		return "spot the odd one out faster."
+	case GamemodeRandom, GamemodeSameSquare, GamemodeOddOneOut:
+		return true
 	default:
-		panic(fmt.Errorf("unknown gamemode %q", g))
+		return false
 	}
 }
 
@@ -118,3 +114,39 @@ type Attempt struct {
 	NumSquares int       `json:"numSquares"`
 	RandState  RandState `json:"randState"`
 }
+
+// Summary is a stored attempt.
+type Summary struct {
+	ID         uuid.UUID `json:"id"`
+	Gamemode   Gamemode  `json:"gamemode"`
+	Kind       Kind      `json:"kind"`
+	DurationMs float64   `json:"duration"`
+	StartedAt  time.Time `json:"startedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+	NumSquares int       `json:"numSquares"`
+	// SessionID is the session which submitted the attempt.
+	SessionID uuid.NullUUID `json:"-"`
+	// RandState is the random stream the attempt was played with.
+	RandState    RandState    `json:"-"`
+	Verification Verification `json:"verification"`
+	// VerificationReason is only shown to the session which submitted the attempt.
+	VerificationReason string `json:"verificationReason,omitempty"`
+}
+
+// Attempt returns the submitted attempt, as far as it is stored.
+func (s Summary) Attempt() Attempt {
+	return Attempt{
+		Gamemode:   s.Gamemode,
+		StartedAt:  s.StartedAt,
+		DurationMs: s.DurationMs,
+		Kind:       s.Kind,
+		NumSquares: s.NumSquares,
+		RandState:  s.RandState,
+	}
+}
+
+// Public removes the fields which only the session which submitted the attempt may see.
+func (s Summary) Public() Summary {
+	s.VerificationReason = ""
+	return s
+}
@@ -0,0 +1,78 @@
+package attempt
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Palette holds the colors of a theme. Keep in sync with src/theme.ts.
+type Palette struct {
+	Background color.RGBA
+	Heading    color.RGBA
+	Body       color.RGBA
+	Assurance  color.RGBA
+	Warning    color.RGBA
+	Danger     color.RGBA
+	Squares    [5]color.RGBA
+}
+
+var palettes = map[Theme]Palette{
+	ThemeCandy: {
+		Background: hex(0x000f1e),
+		Heading:    hex(0xf6f4f3),
+		Body:       hex(0xcecaca),
+		Assurance:  hex(0x0cce6b),
+		Warning:    hex(0xf4fd1f),
+		Danger:     hex(0xef2d56),
+		Squares:    [...]color.RGBA{hex(0xfdc5f5), hex(0xf7aef8), hex(0xb388eb), hex(0x8093f1), hex(0x72ddf7)},
+	},
+	ThemeBlood: {
+		Background: hex(0x080c0c),
+		Heading:    hex(0xf4f9e9),
+		Body:       hex(0xc8ceb9),
+		Assurance:  hex(0x20bf55),
+		Warning:    hex(0xffbe0b),
+		Danger:     hex(0xbc1300),
+		Squares:    [...]color.RGBA{hex(0x7d1128), hex(0xf7a493), hex(0x3c0919), hex(0xbc1300), hex(0xffa061)},
+	},
+	ThemeNoir: {
+		Background: hex(0xd5d5d8),
+		Heading:    hex(0x070a0b),
+		Body:       hex(0x3d3d3d),
+		Assurance:  hex(0x2d8b81),
+		Warning:    hex(0xfde849),
+		Danger:     hex(0xef233c),
+		Squares:    [...]color.RGBA{hex(0xbabbbf), hex(0xb2b3b7), hex(0xadb0b3), hex(0xa8a9ad), hex(0xa4a5a8)},
+	},
+	ThemeCozy: {
+		Background: hex(0xa5dbf5),
+		Heading:    hex(0x06070e),
+		Body:       hex(0x2f3734),
+		Assurance:  hex(0x0c7c59),
+		Warning:    hex(0xffb917),
+		Danger:     hex(0xe0002d),
+		Squares:    [...]color.RGBA{hex(0xc4cea1), hex(0xd9e0a3), hex(0xfdf2b0), hex(0xf3d17c), hex(0xcf9963)},
+	},
+	ThemeFlowers: {
+		Background: hex(0xffe7d1),
+		Heading:    hex(0x110903),
+		Body:       hex(0x4c3529),
+		Assurance:  hex(0x1ec258),
+		Warning:    hex(0xed750c),
+		Danger:     hex(0xe13f09),
+		Squares:    [...]color.RGBA{hex(0xe91c3e), hex(0xff7729), hex(0x88d8dd), hex(0xfee271), hex(0xfef0ec)},
+	},
+}
+
+func (t Theme) Palette() Palette {
+	p, ok := palettes[t]
+	if !ok {
+		panic(fmt.Errorf("unknown theme %q", t))
+	}
+
+	return p
+}
+
+func hex(v uint32) color.RGBA {
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
@@ -15,8 +15,10 @@ import (
 	"github.com/rs/cors"
 	resources "github.com/tmaxmax/popthegrid"
 	"github.com/tmaxmax/popthegrid/internal/cmd/internal"
+	"github.com/tmaxmax/popthegrid/internal/displayname"
 	"github.com/tmaxmax/popthegrid/internal/handler"
 	"github.com/tmaxmax/popthegrid/internal/repo/sqlite"
+	"github.com/tmaxmax/popthegrid/internal/verify"
 	"golang.ngrok.com/ngrok"
 	"golang.ngrok.com/ngrok/config"
 )
@@ -52,24 +54,34 @@ func run() error {
 		return fmt.Errorf("create Vite fragment: %w", err)
 	}
 
+	logOptions := httplog.Options{
+		LogLevel: env.LogLevel,
+		Concise:  true,
+		Writer:   os.Stderr,
+	}
+
+	repo := &sqlite.Repository{DB: db, Logger: httplog.NewLogger("popthegrid", logOptions).With("repository", "sqlite")}
+
 	h := handler.New(handler.Config{
 		AssetsTags:       v.Tags,
 		Assets:           handler.FS{Data: dist, Path: "/assets/"},
 		Public:           handler.FS{Data: os.DirFS("public"), Path: "/static/"},
-		Repository:       &sqlite.Repository{DB: db},
+		Repository:       repo,
 		RecordStorageKey: env.RecordStorageKey,
 		CORS: cors.Options{
 			AllowedOrigins: []string{env.URL},
-			AllowedMethods: []string{http.MethodGet, http.MethodPost},
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete},
+			// Shared records are edited and deleted with their owner token.
+			AllowedHeaders: []string{"Accept", "Content-Type", "Authorization"},
 			Debug:          true,
 		},
-		Logger: httplog.Options{
-			LogLevel: env.LogLevel,
-			Concise:  true,
-			Writer:   os.Stderr,
-		},
+		Logger:        logOptions,
 		SessionSecret: env.HMACSecret,
 		SessionExpiry: env.SessionExpiry,
+		OGImageDir:    env.OGImageDir,
+		ReplayDir:     env.ReplayDir,
+		NamePolicy:    displayname.Policy{Blocklist: env.NameBlocklist},
+		AdminToken:    env.AdminToken,
 		RegisterVite: func(m *http.ServeMux) {
 			viteLocalhostURL, _ := url.Parse("http://localhost:5173")
 			proxy := httputil.NewSingleHostReverseProxy(viteLocalhostURL)
@@ -99,5 +111,10 @@ func run() error {
 
 	fmt.Println("Open at", env.URL)
 
-	return internal.RunServer(ctx, s, l)
+	verifier := verify.Worker{
+		Repository: repo,
+		Logger:     httplog.NewLogger("popthegrid", logOptions).With("worker", "verify"),
+	}
+
+	return internal.RunServer(ctx, s, l, verifier.Run)
 }
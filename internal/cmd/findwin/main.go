@@ -15,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/tmaxmax/popthegrid/internal/srand"
+	"github.com/tmaxmax/popthegrid/internal/verify"
 )
 
 func main() {
@@ -126,14 +127,8 @@ func searchMysteryEncountered(src *srand.Source) iter.Seq[uint64] {
 }
 
 func playMystery(src *srand.Source) (won bool) {
-	for i := range numSquares - 1 {
-		remaining := numSquares - i - 1
-		if remaining > 1 && intn(src.Float64(), remaining+1) == remaining {
-			return false
-		}
-	}
-
-	return true
+	_, won = verify.PlayMystery(src, numSquares)
+	return won
 }
 
 type gleichResult struct {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/go-chi/httplog/v2"
+	"github.com/tmaxmax/popthegrid/internal/displayname"
 )
 
 type Env struct {
@@ -16,6 +17,10 @@ type Env struct {
 	RecordStorageKey string
 	Entrypoint       string
 	Database         string
+	OGImageDir       string
+	ReplayDir        string
+	AdminToken       string
+	NameBlocklist    displayname.Blocklist
 	LogLevel         slog.Level
 	HMACSecret       []byte
 	SessionExpiry    time.Duration
@@ -28,6 +33,10 @@ func Getenv() Env {
 		RecordStorageKey: os.Getenv("VITE_RECORD_STORAGE_KEY"),
 		Entrypoint:       os.Getenv("ENTRYPOINT"),
 		Database:         os.Getenv("DATABASE"),
+		OGImageDir:       os.Getenv("OG_IMAGE_DIR"),
+		ReplayDir:        os.Getenv("REPLAY_DIR"),
+		AdminToken:       os.Getenv("ADMIN_TOKEN"),
+		NameBlocklist:    must(displayname.ReadBlocklistFile(os.Getenv("NAME_BLOCKLIST"))),
 		LogLevel:         httplog.LevelByName(os.Getenv("LOG_LEVEL")),
 		HMACSecret:       must(base64.StdEncoding.DecodeString(os.Getenv("HMAC_SECRET"))),
 		SessionExpiry:    time.Minute * time.Duration(must(strconv.Atoi(os.Getenv("SESSION_EXPIRY")))),
@@ -85,3 +85,19 @@ func CreateDB(ctx context.Context, path string, migrations fs.FS) (*sql.DB, erro
 
 	return db, nil
 }
+
+// OpenDBReadOnly opens an existing database without changing it: it is neither
+// created nor migrated, so it must be at the version this binary expects.
+func OpenDBReadOnly(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(10000)")
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	return db, nil
+}
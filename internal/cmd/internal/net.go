@@ -5,11 +5,23 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
-func RunServer(ctx context.Context, s *http.Server, l net.Listener) error {
-	shutdownError := make(chan error)
+// RunServer serves until the context is canceled. The given tasks run alongside
+// the server with the same context and are waited for before returning.
+func RunServer(ctx context.Context, s *http.Server, l net.Listener, tasks ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	taskErrors := make([]error, len(tasks))
+	for i, task := range tasks {
+		wg.Go(func() { taskErrors[i] = task(ctx) })
+	}
+
+	shutdownError := make(chan error, 1)
 
 	go func() {
 		<-ctx.Done()
@@ -28,10 +40,15 @@ func RunServer(ctx context.Context, s *http.Server, l net.Listener) error {
 	}
 
 	if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+		cancel()
+		wg.Wait()
 		return err
 	}
 
-	return <-shutdownError
+	err = <-shutdownError
+	wg.Wait()
+
+	return errors.Join(append(taskErrors, err)...)
 }
 
 func LocalIP() string {
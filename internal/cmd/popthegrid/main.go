@@ -14,12 +14,21 @@ import (
 	"github.com/rs/cors"
 	resources "github.com/tmaxmax/popthegrid"
 	"github.com/tmaxmax/popthegrid/internal/cmd/internal"
+	"github.com/tmaxmax/popthegrid/internal/displayname"
 	"github.com/tmaxmax/popthegrid/internal/handler"
 	"github.com/tmaxmax/popthegrid/internal/repo/sqlite"
+	"github.com/tmaxmax/popthegrid/internal/verify"
 )
 
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		err = replayAttempt(os.Args[2:])
+	} else {
+		err = run()
+	}
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -48,24 +57,34 @@ func run() error {
 		return fmt.Errorf("create Vite fragment: %w", err)
 	}
 
+	logOptions := httplog.Options{
+		LogLevel: env.LogLevel,
+		JSON:     true,
+		Concise:  true,
+		Writer:   os.Stderr,
+	}
+
+	repo := &sqlite.Repository{DB: db, Logger: httplog.NewLogger("popthegrid", logOptions).With("repository", "sqlite")}
+
 	h := handler.New(handler.Config{
 		AssetsTags:       v.Tags,
 		Assets:           handler.FS{Data: assets, Path: "/assets/"},
 		Public:           handler.FS{Data: public, Path: "/static/"},
-		Repository:       &sqlite.Repository{DB: db},
+		Repository:       repo,
 		RecordStorageKey: env.RecordStorageKey,
 		CORS: cors.Options{
 			AllowedOrigins: []string{env.URL},
-			AllowedMethods: []string{http.MethodGet, http.MethodPost},
-		},
-		Logger: httplog.Options{
-			LogLevel: env.LogLevel,
-			JSON:     true,
-			Concise:  true,
-			Writer:   os.Stderr,
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete},
+			// Shared records are edited and deleted with their owner token.
+			AllowedHeaders: []string{"Accept", "Content-Type", "Authorization"},
 		},
+		Logger:        logOptions,
 		SessionSecret: env.HMACSecret,
 		SessionExpiry: env.SessionExpiry,
+		OGImageDir:    env.OGImageDir,
+		ReplayDir:     env.ReplayDir,
+		NamePolicy:    displayname.Policy{Blocklist: env.NameBlocklist},
+		AdminToken:    env.AdminToken,
 	})
 
 	s := &http.Server{
@@ -74,5 +93,10 @@ func run() error {
 		ReadTimeout: time.Second * 10,
 	}
 
-	return internal.RunServer(ctx, s, nil)
+	verifier := verify.Worker{
+		Repository: repo,
+		Logger:     httplog.NewLogger("popthegrid", logOptions).With("worker", "verify"),
+	}
+
+	return internal.RunServer(ctx, s, nil, verifier.Run)
 }
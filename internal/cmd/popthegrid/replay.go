@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/cmd/internal"
+	"github.com/tmaxmax/popthegrid/internal/replay"
+	"github.com/tmaxmax/popthegrid/internal/repo/sqlite"
+)
+
+// replayAttempt renders an animated replay of a stored attempt:
+//
+//	popthegrid replay [-db path] [-o file] [-cell px] [-frames n] <attempt ID>
+func replayAttempt(args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var dbPath, out string
+	var opts replay.Options
+
+	f := flag.NewFlagSet("replay", flag.ContinueOnError)
+	f.StringVar(&dbPath, "db", os.Getenv("DATABASE"), "the database to read the attempt from")
+	f.StringVar(&out, "o", "", "the file to write the GIF to (default <attempt ID>.gif)")
+	f.IntVar(&opts.CellSize, "cell", 0, "the side length of a grid cell in pixels (default 32)")
+	f.IntVar(&opts.MaxFrames, "frames", 0, "the maximum number of frames, longer games are sped up (default 600)")
+
+	if err := f.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	if f.NArg() != 1 {
+		return errors.New("expected exactly one attempt ID")
+	}
+
+	id, err := uuid.FromString(f.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid attempt ID: %w", err)
+	}
+
+	if out == "" {
+		out = id.String() + ".gif"
+	}
+
+	db, err := internal.OpenDBReadOnly(ctx, dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	repo := &sqlite.Repository{DB: db}
+
+	summary, err := repo.GetAttempt(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get attempt: %w", err)
+	}
+
+	tr, err := repo.GetTrace(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get trace: %w", err)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+
+	att := summary.Attempt()
+	if err := replay.GIF(file, &att, tr, opts); err != nil {
+		file.Close()
+		return fmt.Errorf("render replay: %w", err)
+	}
+
+	return file.Close()
+}
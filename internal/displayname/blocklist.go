@@ -0,0 +1,152 @@
+package displayname
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Blocklist holds folded words which must not appear in names.
+type Blocklist []string
+
+// ParseBlocklist reads a blocklist with one word per line. Empty lines and
+// lines starting with # are skipped.
+func ParseBlocklist(r io.Reader) (Blocklist, error) {
+	var b Blocklist
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if w := fold(line); w != "" {
+			b = append(b, w)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read blocklist: %w", err)
+	}
+
+	return b, nil
+}
+
+// ReadBlocklistFile parses the blocklist in the file at path. It returns an empty blocklist if path is empty.
+func ReadBlocklistFile(path string) (Blocklist, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	return ParseBlocklist(f)
+}
+
+// Matches reports whether any word of the blocklist is in the name. Words are matched
+// anywhere in the name, so innocent names might match too: they are only held for review.
+func (b Blocklist) Matches(name string) bool {
+	folded := fold(name)
+	for _, w := range b {
+		if strings.Contains(folded, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchesWords reports whether a run of consecutive words of s is a word of the blocklist.
+// Words are separated by any other characters than letters, digits and lookalikes, and
+// by uppercase letters following lowercase ones. Unlike Matches, words aren't found inside
+// other words, for texts which are rejected outright instead of being held for review.
+func (b Blocklist) MatchesWords(s string) bool {
+	words := splitWords(s)
+	for i := range words {
+		run := ""
+		for _, w := range words[i:] {
+			run += fold(w)
+			if slices.Contains(b, run) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func splitWords(s string) []string {
+	var words []string
+	var b strings.Builder
+	prevLower := false
+
+	for _, r := range s {
+		_, lookalike := lookalikes[r]
+		if !lookalike && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+			prevLower = false
+			continue
+		}
+
+		if prevLower && unicode.IsUpper(r) {
+			words = append(words, b.String())
+			b.Reset()
+		}
+		prevLower = unicode.IsLower(r)
+		b.WriteRune(r)
+	}
+
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+
+	return words
+}
+
+// lookalikes are the characters commonly used instead of letters to slip words past filters.
+var lookalikes = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// fold reduces s to its lowercase letters and digits, without diacritics and with
+// lookalikes replaced, so that variations of a word fold the same.
+func fold(s string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFKD.String(strings.ToLower(s)) {
+		if l, ok := lookalikes[r]; ok {
+			r = l
+		}
+
+		// Diacritics are decomposed into marks, which are dropped here.
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,74 @@
+// Package displayname cleans up the names players show on their shared records
+// and decides which of them need a review before being shown.
+package displayname
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxLength is the maximum length of names used by policies without one.
+const DefaultMaxLength = 32
+
+type Policy struct {
+	// MaxLength is the maximum number of user-perceived characters of names.
+	MaxLength int
+	// Names matching the blocklist are held for moderation.
+	Blocklist Blocklist
+}
+
+// Check normalizes the name and reports whether it must be held for moderation.
+// It returns an error if the name is not acceptable at all.
+func (p Policy) Check(name string) (string, bool, error) {
+	name = Normalize(name)
+
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+
+	if uniseg.GraphemeClusterCount(name) > maxLength {
+		return "", false, fmt.Errorf("name is longer than %d characters", maxLength)
+	}
+
+	return name, p.Blocklist.Matches(name), nil
+}
+
+// Normalize returns the name in NFC, without control and bidi characters
+// and with runs of whitespace replaced by a single space.
+func Normalize(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+
+	space := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+		case isStripped(r):
+		default:
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+	}
+
+	// Removed characters might have been between a base and its combining marks.
+	return norm.NFC.String(b.String())
+}
+
+func isStripped(r rune) bool {
+	switch r {
+	case '\u200b', '\u2060', '\ufeff': // Invisible spaces and joiners.
+		return true
+	default:
+		// Bidi controls could reorder the text around the name.
+		return unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r)
+	}
+}
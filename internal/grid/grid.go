@@ -0,0 +1,42 @@
+package grid
+
+import "math"
+
+// Layout is an arrangement of squares in a grid.
+type Layout struct {
+	SideLength float64
+	Cols, Rows int
+}
+
+// Fit finds the layout of n squares with the largest side length which fits in a w×h container.
+// This is the algorithm from doc/filling-the-grid.md and doc/rigorous.md, which only needs
+// to check the row counts around sqrt(n/r), where r is the container's aspect ratio.
+// It must be kept in sync with gridDimensions in src/components/Grid.ts.
+func Fit(n int, w, h float64) Layout {
+	r := w / h
+	if r < 1 {
+		l := Fit(n, h, w)
+		l.Cols, l.Rows = l.Rows, l.Cols
+		return l
+	}
+
+	b0 := int(math.Ceil(math.Sqrt(float64(n) / r)))
+
+	var res Layout
+	for b := max(b0-1, 1); b <= b0+1; b++ {
+		if l := layout(n, b, w, h); l.SideLength > res.SideLength {
+			res = l
+		}
+	}
+
+	return res
+}
+
+func layout(n, rows int, w, h float64) Layout {
+	cols := (n + rows - 1) / rows
+	return Layout{
+		SideLength: min(w/float64(cols), h/float64(rows)),
+		Cols:       cols,
+		Rows:       rows,
+	}
+}
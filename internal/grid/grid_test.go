@@ -0,0 +1,54 @@
+package grid
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// fitBruteForce is the O(n) counterpart of Fit, which tries every row count.
+func fitBruteForce(n int, w, h float64) Layout {
+	var res Layout
+	for b := 1; b <= n; b++ {
+		if l := layout(n, b, w, h); l.SideLength > res.SideLength {
+			res = l
+		}
+	}
+
+	return res
+}
+
+// TestFit checks that Fit always finds a side length as large as
+// the one found by trying every possible row count.
+func TestFit(t *testing.T) {
+	const (
+		seed       = 1
+		iterations = 100000
+		maxN       = 1000
+		maxSize    = 4000
+	)
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for range iterations {
+		n := rng.IntN(maxN) + 1
+		w := rng.Float64()*maxSize + 1
+		h := rng.Float64()*maxSize + 1
+
+		fit := Fit(n, w, h)
+		brute := fitBruteForce(n, w, h)
+
+		if !equal(fit.SideLength, brute.SideLength) || fit.Cols*fit.Rows < n || !fits(fit, w, h) {
+			t.Errorf("n=%d w=%v h=%v: fit %+v, brute force %+v", n, w, h, fit, brute)
+		}
+	}
+}
+
+func equal(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*max(a, b)
+}
+
+func fits(l Layout, w, h float64) bool {
+	const eps = 1e-9
+	return float64(l.Cols)*l.SideLength <= w*(1+eps) && float64(l.Rows)*l.SideLength <= h*(1+eps)
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/go-chi/httplog/v2"
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/handler/session"
+	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"github.com/tmaxmax/popthegrid/internal/replay"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+	"schneider.vip/problem"
+)
+
+type attemptHandler struct {
+	atts AttemptsRepository
+}
+
+func (a attemptHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	summary, owned, ok := getAttempt(w, r, a.atts)
+	if !ok {
+		return
+	}
+
+	if !owned {
+		summary = summary.Public()
+	}
+
+	// Attempts waiting for verification are accepted, but not settled yet.
+	statusCode := http.StatusOK
+	if summary.Verification == attempt.VerificationPending {
+		statusCode = http.StatusAccepted
+	}
+
+	// The response depends on the session and the verification may change.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	httpx.JSON(w, summary)
+}
+
+// traceHandler serves the trace of an attempt to the session which submitted it,
+// in the multipart format accepted by submitHandler.
+type traceHandler struct {
+	atts AttemptsRepository
+}
+
+func (t traceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	summary, owned, ok := getAttempt(w, r, t.atts)
+	if !ok {
+		return
+	} else if !owned {
+		problem.Of(http.StatusNotFound).WriteTo(w)
+		return
+	}
+
+	l := httplog.LogEntry(r.Context())
+
+	tr, err := t.atts.GetTrace(r.Context(), summary.ID)
+	if err != nil {
+		l.Error("get trace", "err", err)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	data, pevs, err := encodeTrace(tr)
+	if err != nil {
+		l.Error("encode trace", "err", err)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	mw := multipart.NewWriter(w)
+
+	w.Header().Set("Cache-Control", "private")
+	w.Header().Set("Content-Type", mw.FormDataContentType())
+	w.WriteHeader(http.StatusOK)
+
+	_ = mw.WriteField("trace", string(data))
+	_ = mw.WriteField("pointer-events", string(pevs))
+	_ = mw.Close()
+}
+
+// replayHandler serves an animated replay of an attempt.
+type replayHandler struct {
+	atts  AttemptsRepository
+	cache *replay.Cache
+}
+
+func (h replayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	summary, _, ok := getAttempt(w, r, h.atts)
+	if !ok {
+		return
+	}
+
+	// Only verified attempts are known to replay a game which was actually played.
+	if summary.Verification != attempt.VerificationValid {
+		problem.Of(http.StatusNotFound).Append(problem.Detail("attempt has no replay")).WriteTo(w)
+		return
+	}
+
+	l := httplog.LogEntry(r.Context())
+
+	var renderErr error
+	path, err := h.cache.Path(r.Context(), summary.ID, func(w io.Writer) error {
+		tr, err := h.atts.GetTrace(r.Context(), summary.ID)
+		if err != nil {
+			return fmt.Errorf("get trace: %w", err)
+		}
+
+		att := summary.Attempt()
+		renderErr = replay.GIF(w, &att, tr, replay.Options{})
+		return renderErr
+	})
+	if renderErr != nil {
+		l.Warn("render replay", "err", renderErr, "id", summary.ID)
+		problem.Of(http.StatusUnprocessableEntity).Append(problem.Detail("attempt can't be replayed"), problem.WrapSilent(renderErr)).WriteTo(w)
+		return
+	} else if err != nil {
+		l.Error("render replay", "err", err, "id", summary.ID)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	// Traces never change once submitted.
+	w.Header().Set("Cache-Control", "public, max-age=604800, immutable")
+	w.Header().Set("Content-Type", "image/gif")
+	http.ServeFile(w, r, path)
+}
+
+func encodeTrace(tr *trace.Trace) ([]byte, []byte, error) {
+	data, err := json.Marshal(tr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	pevs, err := tr.PointerEventsPayload()
+	if err != nil {
+		return nil, nil, fmt.Errorf("pointer events: %w", err)
+	}
+
+	return data, pevs, nil
+}
+
+// getAttempt gets the attempt with the ID in the request's path and tells whether
+// it was submitted by the request's session. If it fails, it writes the error response.
+func getAttempt(w http.ResponseWriter, r *http.Request, atts AttemptsRepository) (attempt.Summary, bool, bool) {
+	id, err := uuid.FromString(r.PathValue("id"))
+	if err != nil {
+		problem.Of(http.StatusNotFound).Append(problem.WrapSilent(err)).WriteTo(w)
+		return attempt.Summary{}, false, false
+	}
+
+	summary, err := atts.GetAttempt(r.Context(), id)
+	if err != nil {
+		if rerr := (RepositoryError{}); errors.As(err, &rerr) && rerr.Kind == ErrorNotFound {
+			problem.Of(http.StatusNotFound).Append(problem.WrapSilent(err)).WriteTo(w)
+			return attempt.Summary{}, false, false
+		}
+
+		httplog.LogEntry(r.Context()).Error("get attempt", "err", err, "id", id)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return attempt.Summary{}, false, false
+	}
+
+	sess, ok := session.Get(r.Context())
+	owned := ok && summary.SessionID.Valid && sess.ID == summary.SessionID.UUID
+
+	return summary, owned, true
+}
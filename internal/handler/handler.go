@@ -7,7 +7,10 @@ import (
 	"io/fs"
 	"net/http"
 	"net/netip"
+	"net/url"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,8 +18,13 @@ import (
 	"github.com/go-chi/httplog/v2"
 	"github.com/rs/cors"
 	"github.com/tmaxmax/popthegrid/internal/crypto/altcha"
+	"github.com/tmaxmax/popthegrid/internal/displayname"
 	"github.com/tmaxmax/popthegrid/internal/handler/session"
 	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"github.com/tmaxmax/popthegrid/internal/locale"
+	"github.com/tmaxmax/popthegrid/internal/ogimage"
+	"github.com/tmaxmax/popthegrid/internal/replay"
+	"github.com/tmaxmax/popthegrid/internal/share"
 )
 
 type FS struct {
@@ -58,8 +66,15 @@ type Repository interface {
 	RecordsRepository
 	PingRepository
 	AttemptsRepository
+	LeaderboardRepository
+	ModerationRepository
 }
 
+const codePattern = "GET /{code}"
+
+// maxReplayRenders limits the replays rendered at once, which are expensive.
+const maxReplayRenders = 2
+
 type Config struct {
 	AssetsTags       template.HTML
 	Assets           FS
@@ -70,7 +85,14 @@ type Config struct {
 	Logger           httplog.Options
 	SessionSecret    []byte
 	SessionExpiry    time.Duration
-	RegisterVite     func(*http.ServeMux)
+	// OGImageDir is where the Open Graph images of shared records are cached.
+	OGImageDir string
+	// ReplayDir is where the replays of attempts are cached.
+	ReplayDir  string
+	NamePolicy displayname.Policy
+	// AdminToken authorizes moderators. Moderation is disabled if it's empty.
+	AdminToken   string
+	RegisterVite func(*http.ServeMux)
 }
 
 func New(c Config) http.Handler {
@@ -105,13 +127,23 @@ func New(c Config) http.Handler {
 		index:   index,
 	}
 
-	m.Handle("GET /{code}", codeRenderer{
+	if c.OGImageDir == "" {
+		c.OGImageDir = filepath.Join(os.TempDir(), "popthegrid-og")
+	}
+
+	if c.ReplayDir == "" {
+		c.ReplayDir = filepath.Join(os.TempDir(), "popthegrid-replays")
+	}
+
+	m.Handle("GET /oembed", oEmbedHandler{records: c.Repository})
+	m.Handle("GET /og/{name}", ogImageHandler{records: c.Repository, cache: ogimage.Cache{Dir: c.OGImageDir}})
+	m.Handle(codePattern, codeRenderer{
 		records:    c.Repository,
 		storageKey: c.RecordStorageKey,
 		renderer:   rnd,
 	})
 	m.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
-		rnd.renderIndex(w, r, http.StatusOK, defaultIndex())
+		rnd.renderIndex(w, r, http.StatusOK, defaultIndex(locale.Match(r.Header.Get("Accept-Language"))))
 	})
 
 	pow := altcha.NewHandler(altcha.HandlerConfig{
@@ -132,9 +164,29 @@ func New(c Config) http.Handler {
 
 	m.Handle("POST /session", pow.WithChallenge(sess))
 
-	m.Handle("POST /share", shareHandler{records: c.Repository})
-
-	m.Handle("POST /submit", submitHandler{atts: c.Repository, randKey: c.SessionSecret})
+	m.Handle("POST /share", shareHandler{
+		records:  c.Repository,
+		ownerKey: c.SessionSecret,
+		names:    c.NamePolicy,
+		isRoute:  func(code share.Code) bool { return isRoute(m, code) },
+	})
+	m.Handle("PATCH /share/{code}", editShareHandler{records: c.Repository, ownerKey: c.SessionSecret, names: c.NamePolicy})
+	m.Handle("DELETE /share/{code}", deleteShareHandler{records: c.Repository, ownerKey: c.SessionSecret})
+	m.Handle("GET /share/{code}/stats", shareStatsHandler{records: c.Repository, ownerKey: c.SessionSecret})
+	m.Handle("GET /share/{code}/challengers", challengersHandler{records: c.Repository})
+
+	submit := submitHandler{atts: c.Repository, randKey: c.SessionSecret}
+	m.Handle("POST /submit", submit)
+	m.Handle("GET /attempts/{id}", attemptHandler{atts: c.Repository})
+	m.Handle("GET /attempts/{id}/trace", traceHandler{atts: c.Repository})
+	m.Handle("GET /attempts/{id}/replay.gif", replayHandler{atts: c.Repository, cache: replay.NewCache(c.ReplayDir, maxReplayRenders)})
+	m.Handle("GET /leaderboard", leaderboardHandler{board: c.Repository})
+
+	if c.AdminToken != "" {
+		m.Handle("GET /admin/names", adminOnly(c.AdminToken, heldNamesHandler{names: c.Repository}))
+		m.Handle("POST /admin/names/{id}/approve", adminOnly(c.AdminToken, decideNameHandler{names: c.Repository, approve: true}))
+		m.Handle("POST /admin/names/{id}/reject", adminOnly(c.AdminToken, decideNameHandler{names: c.Repository, approve: false}))
+	}
 
 	if c.RegisterVite != nil {
 		c.RegisterVite(m)
@@ -145,6 +197,11 @@ func New(c Config) http.Handler {
 		c.CORS.Logger = corsLogger{l: logger}
 	}
 
+	// Batches have their own size limit, so they're routed before the other handlers.
+	root := http.NewServeMux()
+	root.Handle("POST /submit/batch", http.MaxBytesHandler(submitBatchHandler{submit}, maxBatchBytes))
+	root.Handle("/", http.MaxBytesHandler(m, 1<<16))
+
 	return chi.Chain(
 		sess.Middleware,
 		middleware.RequestID,
@@ -152,7 +209,22 @@ func New(c Config) http.Handler {
 		httplog.Handler(logger, staticPaths),
 		middleware.Recoverer,
 		cors.New(c.CORS).Handler,
-	).Handler(http.MaxBytesHandler(m, 1<<16))
+	).Handler(root)
+}
+
+// isRoute reports whether the path of the code is served by another handler than the shared records' one.
+// Paths are checked regardless of case, as players might not type links exactly.
+func isRoute(m *http.ServeMux, code share.Code) bool {
+	for _, path := range []string{"/" + string(code), "/" + strings.ToLower(string(code))} {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete} {
+			r := &http.Request{Method: method, URL: &url.URL{Path: path}}
+			if _, pattern := m.Handler(r); pattern != "" && pattern != codePattern {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 type corsLogger struct {
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/httplog/v2"
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"github.com/tmaxmax/popthegrid/internal/leaderboard"
+	"schneider.vip/problem"
+)
+
+type LeaderboardRepository interface {
+	Leaderboard(ctx context.Context, q leaderboard.Query) ([]leaderboard.Entry, error)
+}
+
+const leaderboardPageSize = 25
+
+type leaderboardHandler struct {
+	board LeaderboardRepository
+}
+
+func (h leaderboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q, window, page, err := parseLeaderboardQuery(r)
+	if err != nil {
+		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.Wrap(err)).WriteTo(w)
+		return
+	}
+
+	entries, err := h.board.Leaderboard(r.Context(), q)
+	if err != nil {
+		httplog.LogEntry(r.Context()).Error("get leaderboard", "err", err)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	res := map[string]any{
+		"gamemode": q.Gamemode,
+		"theme":    q.Theme,
+		"window":   window,
+		"page":     page,
+	}
+
+	if len(entries) > leaderboardPageSize {
+		entries = entries[:leaderboardPageSize]
+		res["nextPage"] = page + 1
+	}
+
+	if entries == nil {
+		entries = []leaderboard.Entry{}
+	}
+	res["entries"] = entries
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	httpx.JSON(w, res)
+}
+
+func parseLeaderboardQuery(r *http.Request) (leaderboard.Query, leaderboard.Window, int, error) {
+	v := r.URL.Query()
+
+	gamemode := attempt.Gamemode(v.Get("gamemode"))
+	if err := gamemode.Validate(); err != nil {
+		return leaderboard.Query{}, "", 0, err
+	}
+
+	theme := attempt.Theme(v.Get("theme"))
+	if err := theme.Validate(); err != nil {
+		return leaderboard.Query{}, "", 0, err
+	}
+
+	window := leaderboard.WindowAll
+	if v.Has("window") {
+		window = leaderboard.Window(v.Get("window"))
+		if err := window.Validate(); err != nil {
+			return leaderboard.Query{}, "", 0, err
+		}
+	}
+
+	page := 1
+	if v.Has("page") {
+		var err error
+		if page, err = strconv.Atoi(v.Get("page")); err != nil {
+			return leaderboard.Query{}, "", 0, fmt.Errorf("invalid page: %w", err)
+		} else if page < 1 {
+			return leaderboard.Query{}, "", 0, errors.New("page must be positive")
+		}
+	}
+
+	return leaderboard.Query{
+		Gamemode: gamemode,
+		Theme:    theme,
+		Since:    window.Since(time.Now().UTC().Truncate(0)),
+		Offset:   (page - 1) * leaderboardPageSize,
+		// One more entry is requested to know whether there's a next page.
+		Limit: leaderboardPageSize + 1,
+	}, window, page, nil
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/httplog/v2"
+	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"github.com/tmaxmax/popthegrid/internal/share"
+	"schneider.vip/problem"
+)
+
+// heldNamesHandler lists the names waiting for moderation.
+type heldNamesHandler struct {
+	names ModerationRepository
+}
+
+func (h heldNamesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	names, err := h.names.HeldNames(r.Context())
+	if err != nil {
+		httplog.LogEntry(r.Context()).ErrorContext(r.Context(), "get held names", "err", err)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	if names == nil {
+		names = []share.HeldName{}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	httpx.JSON(w, names)
+}
+
+// decideNameHandler approves or rejects a held name.
+type decideNameHandler struct {
+	names   ModerationRepository
+	approve bool
+}
+
+func (d decideNameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		problem.Of(http.StatusNotFound).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	if err := d.names.DecideName(r.Context(), id, d.approve); err != nil {
+		writeRecordError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminOnly serves the requests bearing the admin token with h.
+func adminOnly(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			problem.Of(http.StatusUnauthorized).WriteTo(w)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			problem.Of(http.StatusForbidden).WriteTo(w)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"github.com/tmaxmax/popthegrid/internal/locale"
+	"github.com/tmaxmax/popthegrid/internal/ogimage"
+	"github.com/tmaxmax/popthegrid/internal/share"
+	"schneider.vip/problem"
+)
+
+type oEmbed struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name,omitempty"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	CacheAge     int    `json:"cache_age"`
+	URL          string `json:"url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// oEmbedHandler describes shared records to oEmbed consumers, as specified at https://oembed.com.
+// Records are embedded as their images: the game can't be embedded in other sites,
+// as its cookies are only sent to the site itself.
+type oEmbedHandler struct {
+	records RecordsRepository
+}
+
+func (o oEmbedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if f := q.Get("format"); f != "" && f != "json" {
+		problem.Of(http.StatusNotImplemented).Append(problem.Detail("only the json format is supported")).WriteTo(w)
+		return
+	}
+
+	code, err := embeddedCode(q.Get("url"), r.Host)
+	if err != nil {
+		problem.Of(http.StatusNotFound).Append(problem.Wrap(err)).WriteTo(w)
+		return
+	}
+
+	record, err := o.records.Get(r.Context(), code)
+	if err != nil {
+		// oEmbed has no status for gone resources.
+		if rerr := (RepositoryError{}); errors.As(err, &rerr) && rerr.Kind == ErrorGone {
+			err = RepositoryError{Kind: ErrorNotFound, Cause: err}
+		}
+
+		writeRecordError(w, r, err)
+		return
+	}
+
+	width, height := ogimage.Width, ogimage.Height
+	if width, err = maxDimension(q, "maxwidth", width); err == nil {
+		height, err = maxDimension(q, "maxheight", height)
+	}
+	if err != nil {
+		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.Wrap(err)).WriteTo(w)
+		return
+	}
+
+	// The image is scaled down to fit both maximums, keeping its aspect ratio.
+	scale := min(float64(width)/ogimage.Width, float64(height)/ogimage.Height)
+	width = max(int(math.Round(ogimage.Width*scale)), 1)
+	height = max(int(math.Round(ogimage.Height*scale)), 1)
+
+	loc := locale.Match(r.Header.Get("Accept-Language"))
+
+	res := oEmbed{
+		Type:         "photo",
+		Version:      "1.0",
+		Title:        record.Description(loc),
+		AuthorName:   record.Name,
+		ProviderName: "Pop the grid!",
+		ProviderURL:  siteURL,
+		CacheAge:     3600,
+		URL:          siteURL + ogImagePath(code, record, loc),
+		Width:        width,
+		Height:       height,
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("Vary", "Accept-Language")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	httpx.JSON(w, res)
+}
+
+// embeddedCode returns the code of the share link to embed. Links must point either
+// to the site or to the host the request was sent to.
+func embeddedCode(link, host string) (share.Code, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+
+	site, _ := url.Parse(siteURL)
+	if (u.Scheme != "http" && u.Scheme != "https") || (u.Host != site.Host && u.Host != host) {
+		return "", fmt.Errorf("url %q is not a share link", link)
+	}
+
+	code := share.Code(strings.TrimPrefix(u.Path, "/"))
+	if err := code.Validate(); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// maxDimension returns the given default dimension, shrunk to the query parameter's value if it's smaller.
+func maxDimension(q url.Values, param string, def int) (int, error) {
+	if !q.Has(param) {
+		return def, nil
+	}
+
+	v, err := strconv.Atoi(q.Get(param))
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", param)
+	}
+
+	return min(v, def), nil
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/httplog/v2"
+	"github.com/tmaxmax/popthegrid/internal/locale"
+	"github.com/tmaxmax/popthegrid/internal/ogimage"
+	"github.com/tmaxmax/popthegrid/internal/share"
+	"schneider.vip/problem"
+)
+
+// ogImageHandler serves the Open Graph image of a shared record, in the language given by the lang parameter.
+type ogImageHandler struct {
+	records RecordsRepository
+	cache   ogimage.Cache
+}
+
+func (o ogImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("name"), ".jpg")
+	code := share.Code(name)
+	if err := code.Validate(); !ok || err != nil {
+		problem.Of(http.StatusNotFound).WriteTo(w)
+		return
+	}
+
+	record, err := o.records.Get(r.Context(), code)
+	if err != nil {
+		if rerr := (RepositoryError{}); errors.As(err, &rerr) && rerr.Kind == ErrorGone {
+			o.cache.Remove(code)
+		}
+
+		writeRecordError(w, r, err)
+		return
+	}
+
+	path, err := o.cache.Path(code, record, locale.Match(r.URL.Query().Get("lang")))
+	if err != nil {
+		httplog.LogEntry(r.Context()).ErrorContext(r.Context(), "render og image", "err", err, "code", code)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	// Links to the image change with the record, see ogimage.Version.
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeFile(w, r, path)
+}
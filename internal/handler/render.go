@@ -7,11 +7,16 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/httplog/v2"
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/handler/session"
 	"github.com/tmaxmax/popthegrid/internal/handler/sessionrand"
+	"github.com/tmaxmax/popthegrid/internal/locale"
+	"github.com/tmaxmax/popthegrid/internal/ogimage"
 	"github.com/tmaxmax/popthegrid/internal/share"
 )
 
@@ -23,54 +28,85 @@ type codeRenderer struct {
 
 func (c codeRenderer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	l := httplog.LogEntry(r.Context())
+	loc := locale.Match(r.Header.Get("Accept-Language"))
 
 	code := share.Code(r.PathValue("code"))
 	if err := code.Validate(); err != nil {
-		c.error(w, r, code, http.StatusNotFound)
+		c.error(w, r, loc, code, http.StatusNotFound)
 		return
 	}
 
 	record, err := c.records.Get(r.Context(), code)
 	if err != nil {
 		var rerr RepositoryError
+		errors.As(err, &rerr)
+
 		var statusCode int
 
-		if errors.As(err, &rerr) && rerr.Kind == ErrorNotFound {
+		switch rerr.Kind {
+		case ErrorNotFound:
 			statusCode = http.StatusNotFound
-		} else {
+		case ErrorGone:
+			// Deleted and expired links don't render their record.
+			statusCode = http.StatusGone
+		default:
 			l.ErrorContext(r.Context(), "get record", "err", err)
 			statusCode = http.StatusInternalServerError
 		}
 
-		c.error(w, r, code, statusCode)
+		c.error(w, r, loc, code, statusCode)
 		return
 	}
 
+	sess, ok := session.Get(r.Context())
+	// Requests without a session cookie have the nil session, which isn't a visitor.
+	sessionID := uuid.NullUUID{UUID: sess.ID, Valid: ok && sess.ID != uuid.Nil}
+	// Creators opening their own links aren't counted. Sessions are short-lived,
+	// so creators are recognized by their player, like when they challenge their records.
+	visitor, creator := sess.Player(), record.PlayerID
+	if !visitor.Valid {
+		visitor = sessionID
+	}
+	if !creator.Valid {
+		creator = record.SessionID
+	}
+	if !visitor.Valid || visitor != creator {
+		if err := c.records.Visit(r.Context(), code, sessionID); err != nil {
+			l.ErrorContext(r.Context(), "count visit", "err", err)
+		}
+	}
+
 	http.SetCookie(w, c.cookie(code, 0))
 
-	data := defaultIndex()
+	data := defaultIndex(loc)
 	data.OG.URL += "/" + string(code)
-	data.Description = record.Description()
+	data.Description = record.Description(loc)
 	data.Objective = data.Description
 
 	b, _ := json.Marshal(record)
 	data.SessionStorage[template.JSStr(c.storageKey)] = template.JSStr(b)
 
+	data.OEmbed = siteURL + "/oembed?" + url.Values{"url": {data.OG.URL}, "format": {"json"}}.Encode()
+
 	img := &data.OG.Image
-	img.Path = fmt.Sprintf("/static/og/%s-%s.jpg", record.Gamemode, record.Theme)
+	img.Path = ogImagePath(code, record, loc)
 	img.Type = "image/jpeg"
-	img.Width = 1200
-	img.Height = 627
-	img.Alt = fmt.Sprintf("Pop the grid: %s", record.Gamemode.Description())
+	img.Width = ogimage.Width
+	img.Height = ogimage.Height
+	img.Alt = fmt.Sprintf("Pop the grid: %s", loc.Challenge(record.Gamemode))
 
 	data.Robots = "noindex"
 
 	c.renderer.renderIndex(w, r, http.StatusOK, data)
 }
 
-func (c codeRenderer) error(w http.ResponseWriter, r *http.Request, code share.Code, statusCode int) {
+func ogImagePath(code share.Code, record share.Record, loc *locale.Locale) string {
+	return fmt.Sprintf("/og/%s.jpg?lang=%s&v=%s", code, loc.Tag, ogimage.Version(record))
+}
+
+func (c codeRenderer) error(w http.ResponseWriter, r *http.Request, loc *locale.Locale, code share.Code, statusCode int) {
 	http.SetCookie(w, c.cookie(code, statusCode))
-	c.renderer.renderIndex(w, r, statusCode, defaultIndex())
+	c.renderer.renderIndex(w, r, statusCode, defaultIndex(loc))
 }
 
 func (codeRenderer) cookie(code share.Code, statusCode int) *http.Cookie {
@@ -91,15 +127,19 @@ func (codeRenderer) cookie(code share.Code, statusCode int) *http.Cookie {
 	return c
 }
 
+const siteURL = "https://popthegrid.com"
+
 //go:embed index.go.html
 var indexHTML string
 
 type indexData struct {
+	Lang        string
 	Description string
 	Objective   string
 	OG          struct {
-		URL   string
-		Image struct {
+		URL    string
+		Locale string
+		Image  struct {
 			Path   string
 			Type   string
 			Width  int
@@ -107,16 +147,20 @@ type indexData struct {
 			Alt    string
 		}
 	}
+	// OEmbed is the URL of the page's oEmbed description, if it has one.
+	OEmbed         string
 	Robots         string
 	SessionStorage map[template.JSStr]template.JSStr
 }
 
-func defaultIndex() indexData {
+func defaultIndex(loc *locale.Locale) indexData {
 	var data indexData
 
-	data.Description = "Pop all the squares in the grid. Will you make it?"
-	data.Objective = "Objective: pop all the squares in the grid. Will you make it?"
-	data.OG.URL = "https://popthegrid.com"
+	data.Lang = loc.Tag.String()
+	data.Description = loc.Description
+	data.Objective = loc.Objective
+	data.OG.URL = siteURL
+	data.OG.Locale = loc.OG
 	data.SessionStorage = map[template.JSStr]template.JSStr{}
 
 	return data
@@ -139,6 +183,8 @@ func (r renderer) renderIndex(w http.ResponseWriter, req *http.Request, statusCo
 
 	data.SessionStorage["rand"] = jsonStr(payload)
 
+	// Pages are rendered in the language the visitor prefers.
+	w.Header().Add("Vary", "Accept-Language")
 	// These headers are required for ensuring an isolated context.
 	w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
 	w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
@@ -4,21 +4,48 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/attempt"
 	"github.com/tmaxmax/popthegrid/internal/share"
 )
 
 type RecordsRepository interface {
 	Save(ctx context.Context, record share.Record) (share.Code, error)
+	// SaveAs saves the record under the given vanity code, if it's not taken.
+	SaveAs(ctx context.Context, record share.Record, code share.Code) error
 	Get(ctx context.Context, code share.Code) (share.Record, error)
+	// Visit counts an open of the record's link by the session, if any.
+	Visit(ctx context.Context, code share.Code, sessionID uuid.NullUUID) error
+	Stats(ctx context.Context, code share.Code) (share.Stats, error)
+	// Challengers returns the best results of the attempts played against the record, best first.
+	Challengers(ctx context.Context, code share.Code, gamemode attempt.Gamemode) ([]share.Challenger, error)
+	// Edit changes the record. Changing the name discards the names of the record held for moderation.
+	Edit(ctx context.Context, code share.Code, edit share.Edit) error
+	Delete(ctx context.Context, code share.Code) error
+	// HoldName holds the name for moderation, replacing the record's previously held name.
+	HoldName(ctx context.Context, code share.Code, name string) error
+}
+
+type ModerationRepository interface {
+	// HeldNames returns the names held for moderation of records which are still up, oldest first.
+	HeldNames(ctx context.Context) ([]share.HeldName, error)
+	// DecideName sets the held name on its record if approved and discards it otherwise.
+	DecideName(ctx context.Context, id int64, approve bool) error
 }
 
 type ErrorKind string
 
 const (
 	ErrorNotFound         ErrorKind = "not found"
+	ErrorGone             ErrorKind = "deleted or expired"
 	ErrorInternal         ErrorKind = "internal"
 	ErrorAlreadySubmitted ErrorKind = "already submitted"
 	ErrorNotWin           ErrorKind = "attempt is not a win"
+	ErrorDataMismatch     ErrorKind = "record data does not match attempt"
+	ErrorRandUsed         ErrorKind = "random configuration is already used"
+	ErrorKeyReused        ErrorKind = "idempotency key was used for a different request"
+	ErrorKeyInProgress    ErrorKind = "a request with the same idempotency key is in progress"
+	ErrorCodeTaken        ErrorKind = "code is taken"
 )
 
 type RepositoryError struct {
@@ -23,12 +23,16 @@ type Handler struct {
 }
 
 func (s Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := s.retrievePlayer(r)
 	sess := Session{
 		ID:        uuid.Must(uuid.NewV4()),
 		CreatedAt: time.Now(),
 		Expiry:    s.Expiry,
+		PlayerID:  p.ID,
 	}
 
+	// Each new session extends the player's expiry.
+	http.SetCookie(w, p.cookie(s.Secret))
 	http.SetCookie(w, sess.cookie(s.Secret))
 	w.WriteHeader(http.StatusOK)
 	httpx.JSON(w, map[string]any{"data": sess.expiry()})
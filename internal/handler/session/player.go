@@ -0,0 +1,63 @@
+package session
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/crypto/macval"
+)
+
+const playerCookieName = "player"
+
+// playerExpiry is how long players keep their identity without starting a session.
+const playerExpiry = 365 * 24 * time.Hour
+
+// player identifies a player across sessions, which are short-lived.
+type player struct {
+	ID uuid.UUID
+}
+
+// playerPrefix keeps sessions signed with the same key from passing as players.
+var playerPrefix = []byte("player:")
+
+func (p player) MarshalBinary() ([]byte, error) {
+	return append(bytes.Clone(playerPrefix), p.ID[:]...), nil
+}
+
+func (p *player) UnmarshalBinary(data []byte) error {
+	id, ok := bytes.CutPrefix(data, playerPrefix)
+	if !ok {
+		return errors.New("not a player")
+	}
+
+	return p.ID.UnmarshalBinary(id)
+}
+
+func (p player) cookie(secret []byte) *http.Cookie {
+	val, _ := macval.To(p, macval.Options{Algorithm: sha256.New, Key: secret})
+
+	return &http.Cookie{
+		Name:     playerCookieName,
+		Value:    val,
+		MaxAge:   int(playerExpiry.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// retrievePlayer returns the player of the request, or a new one if it has none.
+func (s Handler) retrievePlayer(r *http.Request) player {
+	if c, err := r.Cookie(playerCookieName); err == nil {
+		var p player
+		if err := macval.From(c.Value, macval.Options{Algorithm: sha256.New, Key: s.Secret}, &p); err == nil {
+			return p
+		}
+	}
+
+	return player{ID: uuid.Must(uuid.NewV4())}
+}
@@ -17,6 +17,14 @@ type Session struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	Expiry    time.Duration
+	// PlayerID is kept by every session of a player. It is uuid.Nil for
+	// sessions started before players were identified.
+	PlayerID uuid.UUID
+}
+
+// Player returns the player which started the session, if known.
+func (s Session) Player() uuid.NullUUID {
+	return uuid.NullUUID{UUID: s.PlayerID, Valid: s.PlayerID != uuid.Nil}
 }
 
 type expiryMs struct {
@@ -3,9 +3,14 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/httplog/v2"
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/displayname"
 	"github.com/tmaxmax/popthegrid/internal/handler/session"
 	"github.com/tmaxmax/popthegrid/internal/httpx"
 	"github.com/tmaxmax/popthegrid/internal/share"
@@ -13,23 +18,50 @@ import (
 )
 
 type shareHandler struct {
-	records RecordsRepository
+	records  RecordsRepository
+	ownerKey []byte
+	names    displayname.Policy
+	// isRoute reports whether links with the code would be served by another route.
+	isRoute func(share.Code) bool
+}
+
+type shareInput struct {
+	share.Record
+	// Code is the vanity code requested for the record. A random code is used if it's empty.
+	Code share.Code `json:"code"`
 }
 
 func (s shareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if _, ok := session.Get(r.Context()); !ok {
+	sess, ok := session.Get(r.Context())
+	if !ok {
 		problem.Of(http.StatusUnauthorized).WriteTo(w)
 		return
 	}
 
-	record, ok := s.unmarshalPost(w, r)
+	input, ok := s.unmarshalPost(w, r)
+	if !ok {
+		return
+	}
+
+	record, code := input.Record, input.Code
+
+	held, ok := checkName(w, r, s.names, &record.Name)
 	if !ok {
 		return
 	}
 
+	record.SessionID = uuid.NullUUID{UUID: sess.ID, Valid: true}
+	record.PlayerID = sess.Player()
+
 	l := httplog.LogEntry(r.Context())
 
-	code, err := s.records.Save(r.Context(), record)
+	var err error
+	if code != "" {
+		err = s.records.SaveAs(r.Context(), record, code)
+	} else {
+		code, err = s.records.Save(r.Context(), record)
+	}
+
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		var opts []problem.Option
@@ -38,8 +70,10 @@ func (s shareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			switch rerr.Kind {
 			case ErrorNotFound:
 				statusCode = http.StatusNotFound
-			case ErrorAlreadySubmitted, ErrorNotWin:
+			case ErrorAlreadySubmitted, ErrorNotWin, ErrorDataMismatch:
 				statusCode = http.StatusBadRequest
+			case ErrorCodeTaken:
+				statusCode = http.StatusConflict
 			default:
 			}
 
@@ -56,31 +90,279 @@ func (s shareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	res := map[string]any{
+		"code":       code,
+		"ownerToken": share.OwnerToken{Code: code}.Sign(s.ownerKey),
+	}
+
+	if held != "" {
+		// The link works without the name, so it's shared even if holding the name fails.
+		if err := s.records.HoldName(r.Context(), code, held); err != nil {
+			l.ErrorContext(r.Context(), "hold name", "err", err, "code", code)
+		} else {
+			res["nameHeld"] = true
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	httpx.JSON(w, map[string]any{
-		"code": code,
-	})
+	httpx.JSON(w, res)
 }
 
-func (s shareHandler) unmarshalPost(w http.ResponseWriter, r *http.Request) (share.Record, bool) {
+func (s shareHandler) unmarshalPost(w http.ResponseWriter, r *http.Request) (shareInput, bool) {
 	defer r.Body.Close()
 
 	l := httplog.LogEntry(r.Context())
 
-	var input share.Record
+	var input shareInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		l.WarnContext(r.Context(), "decode input", "err", err)
 		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input, must be JSON")).WriteTo(w)
-		return share.Record{}, false
+		return shareInput{}, false
 	}
 
 	if err := input.Validate(); err != nil {
 		l.WarnContext(r.Context(), "validation error", "err", err)
 		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.Wrap(err)).WriteTo(w)
-		return share.Record{}, false
+		return shareInput{}, false
+	}
+
+	if input.Code != "" {
+		err := input.Code.ValidateVanity(s.names.Blocklist)
+		if err == nil && s.isRoute(input.Code) {
+			err = fmt.Errorf("code %q is reserved", input.Code)
+		}
+
+		if err != nil {
+			problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid code"), problem.Wrap(err)).WriteTo(w)
+			return shareInput{}, false
+		}
 	}
 
 	return input, true
 }
+
+// shareStatsHandler serves the stats of a shared record to the bearer of its owner token.
+type shareStatsHandler struct {
+	records  RecordsRepository
+	ownerKey []byte
+}
+
+func (s shareStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code, ok := ownedCode(w, r, s.ownerKey)
+	if !ok {
+		return
+	}
+
+	if _, err := s.records.Get(r.Context(), code); err != nil {
+		writeRecordError(w, r, err)
+		return
+	}
+
+	l := httplog.LogEntry(r.Context())
+
+	stats, err := s.records.Stats(r.Context(), code)
+	if err != nil {
+		l.ErrorContext(r.Context(), "get stats", "err", err)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	httpx.JSON(w, stats)
+}
+
+// challengersHandler serves the best results of the players who challenged a shared record.
+type challengersHandler struct {
+	records RecordsRepository
+}
+
+func (c challengersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code, record, ok := getRecord(w, r, c.records)
+	if !ok {
+		return
+	}
+
+	l := httplog.LogEntry(r.Context())
+
+	challengers, err := c.records.Challengers(r.Context(), code, record.Gamemode)
+	if err != nil {
+		l.ErrorContext(r.Context(), "get challengers", "err", err)
+		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	for i := range challengers {
+		challengers[i].Beats = record.BeatenBy(challengers[i].Data)
+	}
+
+	if challengers == nil {
+		challengers = []share.Challenger{}
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	httpx.JSON(w, map[string]any{
+		"record":      record,
+		"challengers": challengers,
+	})
+}
+
+// editShareHandler changes a shared record on behalf of the bearer of its owner token.
+type editShareHandler struct {
+	records  RecordsRepository
+	ownerKey []byte
+	names    displayname.Policy
+}
+
+func (e editShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code, ok := ownedCode(w, r, e.ownerKey)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+
+	var edit share.Edit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input, must be JSON")).WriteTo(w)
+		return
+	}
+
+	if err := edit.Validate(time.Now()); err != nil {
+		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.Wrap(err)).WriteTo(w)
+		return
+	}
+
+	var held string
+	if edit.Name != nil {
+		if held, ok = checkName(w, r, e.names, edit.Name); !ok {
+			return
+		}
+	}
+
+	if err := e.records.Edit(r.Context(), code, edit); err != nil {
+		writeRecordError(w, r, err)
+		return
+	}
+
+	if held != "" {
+		if err := e.records.HoldName(r.Context(), code, held); err != nil {
+			writeRecordError(w, r, err)
+			return
+		}
+
+		// The new name is shown once a moderator approves it.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteShareHandler takes down a shared record on behalf of the bearer of its owner token.
+type deleteShareHandler struct {
+	records  RecordsRepository
+	ownerKey []byte
+}
+
+func (d deleteShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code, ok := ownedCode(w, r, d.ownerKey)
+	if !ok {
+		return
+	}
+
+	if err := d.records.Delete(r.Context(), code); err != nil {
+		writeRecordError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedCode returns the code in the request's path, if the request bears its owner token.
+// If it doesn't, it writes the error response.
+func ownedCode(w http.ResponseWriter, r *http.Request, key []byte) (share.Code, bool) {
+	code := share.Code(r.PathValue("code"))
+	if err := code.Validate(); err != nil {
+		problem.Of(http.StatusNotFound).Append(problem.WrapSilent(err)).WriteTo(w)
+		return "", false
+	}
+
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		problem.Of(http.StatusUnauthorized).Append(problem.Detail("owner token required")).WriteTo(w)
+		return "", false
+	}
+
+	token, err := share.ParseOwnerToken(auth, key)
+	if err != nil || token.Code != code {
+		problem.Of(http.StatusForbidden).Append(problem.Detail("invalid owner token"), problem.WrapSilent(err)).WriteTo(w)
+		return "", false
+	}
+
+	return code, true
+}
+
+// checkName normalizes the name in place according to the policy. If the name must be held
+// for moderation, it is cleared and returned. If the name is not acceptable, it writes the error response.
+func checkName(w http.ResponseWriter, r *http.Request, policy displayname.Policy, name *string) (string, bool) {
+	normalized, held, err := policy.Check(*name)
+	if err != nil {
+		httplog.LogEntry(r.Context()).WarnContext(r.Context(), "invalid name", "err", err)
+		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid name"), problem.Wrap(err)).WriteTo(w)
+		return "", false
+	}
+
+	if held {
+		*name = ""
+		return normalized, true
+	}
+
+	*name = normalized
+
+	return "", true
+}
+
+// getRecord gets the record with the code in the request's path.
+// If it fails, it writes the error response.
+func getRecord(w http.ResponseWriter, r *http.Request, records RecordsRepository) (share.Code, share.Record, bool) {
+	code := share.Code(r.PathValue("code"))
+	if err := code.Validate(); err != nil {
+		problem.Of(http.StatusNotFound).Append(problem.WrapSilent(err)).WriteTo(w)
+		return "", share.Record{}, false
+	}
+
+	record, err := records.Get(r.Context(), code)
+	if err != nil {
+		writeRecordError(w, r, err)
+		return "", share.Record{}, false
+	}
+
+	return code, record, true
+}
+
+func writeRecordError(w http.ResponseWriter, r *http.Request, err error) {
+	statusCode := http.StatusInternalServerError
+	if rerr := (RepositoryError{}); errors.As(err, &rerr) {
+		switch rerr.Kind {
+		case ErrorNotFound:
+			statusCode = http.StatusNotFound
+		case ErrorGone:
+			statusCode = http.StatusGone
+		default:
+		}
+	}
+
+	if statusCode == http.StatusInternalServerError {
+		httplog.LogEntry(r.Context()).ErrorContext(r.Context(), "record", "err", err)
+	}
+
+	problem.Of(statusCode).Append(problem.WrapSilent(err)).WriteTo(w)
+}
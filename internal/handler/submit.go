@@ -2,9 +2,12 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"time"
@@ -15,12 +18,33 @@ import (
 	"github.com/tmaxmax/popthegrid/internal/handler/session"
 	"github.com/tmaxmax/popthegrid/internal/handler/sessionrand"
 	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"github.com/tmaxmax/popthegrid/internal/share"
 	"github.com/tmaxmax/popthegrid/internal/trace"
+	"github.com/tmaxmax/popthegrid/internal/verify"
 	"schneider.vip/problem"
 )
 
+// Submission is an attempt submitted by a session.
+type Submission struct {
+	Attempt   *attempt.Attempt
+	Trace     *trace.Trace
+	Result    verify.Result
+	SessionID uuid.UUID
+	// PlayerID is the player of the session, by which wins are ranked on leaderboards.
+	PlayerID uuid.NullUUID
+	// IdempotencyKey, if set, makes retries of the submission return the ID of the attempt
+	// saved by the first one. It is unique per session.
+	IdempotencyKey string
+	// PayloadHash tells retries apart from other submissions with the same key.
+	PayloadHash []byte
+	// Challenge is the code of the shared record the attempt was played against, if any.
+	Challenge share.Code
+}
+
 type AttemptsRepository interface {
-	Submit(ctx context.Context, att *attempt.Attempt, tr *trace.Trace) (uuid.UUID, error)
+	Submit(ctx context.Context, sub Submission) (uuid.UUID, error)
+	GetAttempt(ctx context.Context, id uuid.UUID) (attempt.Summary, error)
+	GetTrace(ctx context.Context, id uuid.UUID) (*trace.Trace, error)
 }
 
 type submitHandler struct {
@@ -29,31 +53,28 @@ type submitHandler struct {
 }
 
 func (s submitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if _, ok := session.Get(r.Context()); !ok {
+	sess, ok := session.Get(r.Context())
+	if !ok {
 		problem.Of(http.StatusUnauthorized).WriteTo(w)
 		return
 	}
 
 	l := httplog.LogEntry(r.Context())
 
-	in, err := s.unmarshal(r)
+	in := newSubmitInput()
+	err := readParts(r, in.add)
+	if err == nil {
+		err = in.finish(r.Header.Get("Idempotency-Key"))
+	}
 	if err != nil {
 		l.Warn("invalid input", "err", err)
 		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.WrapSilent(err)).WriteTo(w)
 		return
 	}
 
-	if in.RandSignature.Signature != "" && !sessionrand.Verify(in.Attempt.RandState.Rand, in.RandSignature, s.randKey, time.Now()) {
-		problem.Of(http.StatusUnauthorized).Append(problem.Detail("attempt random function does not match signature")).WriteTo(w)
-		return
-	}
-
-	// TODO: verify attempt
-
-	id, err := s.atts.Submit(r.Context(), &in.Attempt, &in.Trace)
-	if err != nil {
-		httplog.LogEntry(r.Context()).Error("save attempt", "err", err, "attempt", in.Attempt)
-		problem.Of(http.StatusInternalServerError).Append(problem.WrapSilent(err)).WriteTo(w)
+	id, prob := s.submit(r.Context(), sess, in)
+	if prob != nil {
+		prob.WriteTo(w)
 		return
 	}
 
@@ -65,57 +86,189 @@ func (s submitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// submit saves the attempt, returning the problem which prevented it otherwise.
+func (s submitHandler) submit(ctx context.Context, sess session.Session, in *submitInput) (uuid.UUID, *problem.Problem) {
+	l := httplog.LogEntry(ctx)
+
+	if in.RandSignature.Signature != "" && !sessionrand.Verify(in.Attempt.RandState.Rand, in.RandSignature, s.randKey, time.Now()) {
+		return uuid.Nil, problem.Of(http.StatusUnauthorized).Append(problem.Detail("attempt random function does not match signature"))
+	}
+
+	// Attempts are verified in the background by a verify.Worker.
+	// Those which can't be verified without the signature are settled here.
+	// The others are replayed to find which part of the random stream they used,
+	// so the repository can reject attempts which reuse it.
+	res := verify.Result{Verification: attempt.VerificationPending, RandEnd: in.Attempt.RandState.Offset}
+	if in.Attempt.Gamemode.UsesRand() {
+		if in.RandSignature.Signature == "" {
+			res = verify.Invalid(errors.New("random configuration is not signed"))
+		} else if end, err := verify.RandEnd(&in.Attempt, &in.Trace); err != nil {
+			res = verify.Invalid(err)
+		} else {
+			res.RandEnd = end
+		}
+	}
+
+	id, err := s.atts.Submit(ctx, Submission{
+		Attempt:        &in.Attempt,
+		Trace:          &in.Trace,
+		Result:         res,
+		SessionID:      sess.ID,
+		PlayerID:       sess.Player(),
+		IdempotencyKey: in.IdempotencyKey,
+		PayloadHash:    in.PayloadHash,
+		Challenge:      in.Challenge,
+	})
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		var opts []problem.Option
+
+		if rerr := (RepositoryError{}); errors.As(err, &rerr) {
+			switch rerr.Kind {
+			case ErrorRandUsed, ErrorKeyInProgress:
+				statusCode = http.StatusConflict
+			case ErrorKeyReused:
+				statusCode = http.StatusUnprocessableEntity
+			default:
+			}
+
+			if statusCode != http.StatusInternalServerError {
+				opts = append(opts, problem.Detail(string(rerr.Kind)))
+			}
+		}
+
+		if statusCode == http.StatusInternalServerError {
+			l.Error("save attempt", "err", err, "attempt", in.Attempt)
+		} else {
+			l.Warn("attempt rejected", "err", err, "attempt", in.Attempt)
+		}
+
+		opts = append(opts, problem.WrapSilent(err))
+		return uuid.Nil, problem.Of(statusCode).Append(opts...)
+	}
+
+	return id, nil
+}
+
 type submitInput struct {
-	Attempt       attempt.Attempt
-	Trace         trace.Trace
-	RandSignature sessionrand.Signature
+	Attempt        attempt.Attempt
+	Trace          trace.Trace
+	RandSignature  sessionrand.Signature
+	IdempotencyKey string
+	PayloadHash    []byte
+	Challenge      share.Code
+
+	pointerEvents []byte
+	hasAttempt    bool
+	hasTrace      bool
+	// hash covers every part except the idempotency key, in order.
+	hash hash.Hash
 }
 
-func (submitHandler) unmarshal(r *http.Request) (*submitInput, error) {
-	var in submitInput
-	var pevs []byte
+func newSubmitInput() *submitInput {
+	return &submitInput{hash: sha256.New()}
+}
 
+// add decodes the part of the submission with the given form name.
+func (in *submitInput) add(name string, data []byte) error {
+	if name != "idempotency-key" {
+		in.hash.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(name))))
+		in.hash.Write([]byte(name))
+		in.hash.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(data))))
+		in.hash.Write(data)
+	}
+
+	switch name {
+	case "attempt":
+		if err := json.Unmarshal(data, &in.Attempt); err != nil {
+			return fmt.Errorf("decode attempt: %w", err)
+		}
+		in.hasAttempt = true
+	case "trace":
+		if err := json.Unmarshal(data, &in.Trace); err != nil {
+			return fmt.Errorf("decode trace: %w", err)
+		}
+		in.hasTrace = true
+	case "pointer-events":
+		in.pointerEvents = data
+	case "rand":
+		if err := json.Unmarshal(data, &in.RandSignature); err != nil {
+			return fmt.Errorf("decode rand signature: %w", err)
+		}
+	case "idempotency-key":
+		in.IdempotencyKey = string(data)
+	case "challenge":
+		in.Challenge = share.Code(data)
+		if err := in.Challenge.Validate(); err != nil {
+			return fmt.Errorf("invalid challenge: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown form value %q", name)
+	}
+
+	return nil
+}
+
+const maxIdempotencyKeyLength = 255
+
+// finish is called after all parts were added. The idempotency key may also be given
+// outside of the form, in which case it must match the form value, if any.
+func (in *submitInput) finish(idempotencyKey string) error {
+	if !in.hasAttempt || !in.hasTrace {
+		return errors.New("attempt and trace are required")
+	}
+
+	if err := in.Trace.SetPointerEvents(in.pointerEvents); err != nil {
+		return fmt.Errorf("set trace pointer events: %w", err)
+	}
+
+	in.PayloadHash = in.hash.Sum(nil)
+
+	if idempotencyKey == "" {
+		idempotencyKey = in.IdempotencyKey
+	} else if in.IdempotencyKey != "" && in.IdempotencyKey != idempotencyKey {
+		return errors.New("idempotency key header and form value differ")
+	}
+
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key is longer than %d bytes", maxIdempotencyKeyLength)
+	}
+
+	for i := range len(idempotencyKey) {
+		if idempotencyKey[i] < ' ' || idempotencyKey[i] > '~' {
+			return errors.New("idempotency key contains invalid characters")
+		}
+	}
+
+	in.IdempotencyKey = idempotencyKey
+	return nil
+}
+
+// readParts calls add with every part of the request's multipart form.
+// Reading stops at the first error returned by add.
+func readParts(r *http.Request, add func(name string, data []byte) error) error {
 	rd, err := r.MultipartReader()
 	if err != nil {
-		return nil, fmt.Errorf("open multipart form: %w", err)
+		return fmt.Errorf("open multipart form: %w", err)
 	}
 
 	for {
 		p, err := rd.NextPart()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
-				break
+				return nil
 			}
 
-			return nil, fmt.Errorf("read multipart form: %w", err)
+			return fmt.Errorf("read multipart form: %w", err)
 		}
 
-		switch p.FormName() {
-		case "attempt":
-			if err := json.NewDecoder(p).Decode(&in.Attempt); err != nil {
-				return nil, fmt.Errorf("decode attempt: %w", err)
-			}
-		case "trace":
-			if err := json.NewDecoder(p).Decode(&in.Trace); err != nil {
-				return nil, fmt.Errorf("decode trace: %w", err)
-			}
-		case "pointer-events":
-			pevs, err = io.ReadAll(p)
-			if err != nil {
-				return nil, fmt.Errorf("read pointer events: %w", err)
-			}
-		case "rand":
-			if err := json.NewDecoder(p).Decode(&in.RandSignature); err != nil {
-				return nil, fmt.Errorf("decode rand signature: %w", err)
-			}
-		default:
-			return nil, fmt.Errorf("unknown form value %q", p.FormName())
+		data, err := io.ReadAll(p)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p.FormName(), err)
 		}
-	}
 
-	if err := in.Trace.SetPointerEvents(pevs); err != nil {
-		return nil, fmt.Errorf("set trace pointer events: %w", err)
+		if err := add(p.FormName(), data); err != nil {
+			return err
+		}
 	}
-
-	return &in, nil
 }
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/httplog/v2"
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/handler/session"
+	"github.com/tmaxmax/popthegrid/internal/httpx"
+	"schneider.vip/problem"
+)
+
+const (
+	maxBatchSize  = 50
+	maxBatchBytes = 4 << 20
+)
+
+// submitBatchHandler submits many attempts at once, for example the ones played offline.
+// The parts of the i-th attempt are named like the ones accepted by submitHandler,
+// followed by the index in brackets: "attempt[i]", "trace[i]", "idempotency-key[i]" etc.
+// Attempts are submitted independently, in order, and a result is returned for each.
+// Batches whose indices have gaps or which repeat a part are rejected as a whole,
+// as the client which sent them is broken.
+type submitBatchHandler struct {
+	submitHandler
+}
+
+type batchResult struct {
+	ID      uuid.UUID        `json:"id,omitzero"`
+	Problem *problem.Problem `json:"problem,omitempty"`
+}
+
+func (s submitBatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sess, ok := session.Get(r.Context())
+	if !ok {
+		problem.Of(http.StatusUnauthorized).WriteTo(w)
+		return
+	}
+
+	l := httplog.LogEntry(r.Context())
+
+	var items []*submitInput
+	var errs [maxBatchSize]error
+	var received [maxBatchSize]bool
+	seen := map[string]bool{}
+
+	err := readParts(r, func(name string, data []byte) error {
+		field, i, err := batchField(name)
+		if err != nil {
+			return err
+		} else if seen[name] {
+			return fmt.Errorf("form value %q is given more than once", name)
+		}
+		seen[name], received[i] = true, true
+
+		for len(items) <= i {
+			items = append(items, newSubmitInput())
+		}
+
+		if errs[i] == nil {
+			errs[i] = items[i].add(field, data)
+		}
+
+		return nil
+	})
+	if err == nil && len(items) == 0 {
+		err = errors.New("no attempts")
+	}
+	for i := range items {
+		if err == nil && !received[i] {
+			err = fmt.Errorf("attempt %d is missing", i)
+		}
+	}
+	if err != nil {
+		l.Warn("invalid input", "err", err)
+		problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.WrapSilent(err)).WriteTo(w)
+		return
+	}
+
+	results := make([]batchResult, len(items))
+	for i, in := range items {
+		err := errs[i]
+		if err == nil {
+			err = in.finish("")
+		}
+
+		if err != nil {
+			l.Warn("invalid input", "err", err, "index", i)
+			results[i].Problem = problem.Of(http.StatusBadRequest).Append(problem.Detail("invalid input"), problem.WrapSilent(err))
+			continue
+		}
+
+		results[i].ID, results[i].Problem = s.submit(r.Context(), sess, in)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	httpx.JSON(w, map[string]any{
+		"results": results,
+	})
+}
+
+// batchField splits a form name like "attempt[3]" into the field name and the index.
+func batchField(name string) (string, int, error) {
+	field, index, ok := strings.Cut(name, "[")
+	if ok {
+		index, ok = strings.CutSuffix(index, "]")
+	}
+	if !ok {
+		return "", 0, fmt.Errorf("form value %q has no index", name)
+	}
+
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 0 || strconv.Itoa(i) != index {
+		return "", 0, fmt.Errorf("form value %q has invalid index", name)
+	} else if i >= maxBatchSize {
+		return "", 0, fmt.Errorf("at most %d attempts can be submitted at once", maxBatchSize)
+	}
+
+	return field, i, nil
+}
@@ -0,0 +1,74 @@
+// Package leaderboard ranks the verified wins of players.
+//
+// Every verified win counts. Wins are attributed to the player whose session submitted
+// them and count on the leaderboard of the theme they were played in. The name of the
+// player's latest shared record of the gamemode is their display name: players who
+// haven't shared one are anonymous.
+package leaderboard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/share"
+)
+
+type Window string
+
+const (
+	WindowDay  Window = "day"
+	WindowWeek Window = "week"
+	WindowAll  Window = "all"
+)
+
+func (w Window) Validate() error {
+	switch w {
+	case WindowDay, WindowWeek, WindowAll:
+		return nil
+	default:
+		return fmt.Errorf("invalid window %q", w)
+	}
+}
+
+// Since returns the earliest time at which attempts in the window were submitted.
+// It returns the zero time for WindowAll.
+func (w Window) Since(now time.Time) time.Time {
+	switch w {
+	case WindowDay:
+		return now.Add(-24 * time.Hour)
+	case WindowWeek:
+		return now.Add(-7 * 24 * time.Hour)
+	default:
+		return time.Time{}
+	}
+}
+
+// RanksWins reports whether players of the gamemode are ranked by their number of wins,
+// instead of by their fastest win.
+func RanksWins(g attempt.Gamemode) bool {
+	return g == attempt.GamemodeRandom
+}
+
+type Query struct {
+	Gamemode attempt.Gamemode
+	Theme    attempt.Theme
+	Since    time.Time
+	Offset   int
+	Limit    int
+}
+
+// Entry is the standing of a player.
+type Entry struct {
+	Rank int `json:"rank"`
+	// Name is empty for anonymous players.
+	Name string `json:"name,omitempty"`
+	// Code is the record shared by the player, empty for anonymous players.
+	Code share.Code `json:"code,omitempty"`
+	// In milliseconds; gamemodes ranked by the fastest win.
+	DurationMs float64 `json:"duration,omitempty"`
+	// Gamemodes ranked by the number of wins.
+	NumWins int `json:"numWins,omitempty"`
+	// When is when the ranked win was started, or the last win for gamemodes ranked by wins.
+	When time.Time `json:"when"`
+}
@@ -0,0 +1,95 @@
+// Package locale holds the messages shown to players, in every language the game speaks.
+package locale
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"golang.org/x/text/language"
+)
+
+type Locale struct {
+	Tag language.Tag
+	// OG is the locale in the format expected by the og:locale property.
+	OG string
+
+	Description string
+	Objective   string
+
+	// world introduces the record of the named player, or of the player's friend if name is empty.
+	world func(name string) string
+	// didIt introduces the score of the named player on images.
+	didIt func(name string) string
+	times func(n int) string
+	wins  func(n int) string
+	// records describe the records of each gamemode, given the world and the score.
+	records map[attempt.Gamemode]string
+	// challenges dare players to beat the records of each gamemode. They must fit on a line of the record images.
+	challenges map[attempt.Gamemode]string
+	duration   func(d time.Duration) string
+}
+
+// Record describes the record of the named player. The score is either
+// the result of Times or of Duration, depending on the gamemode.
+func (l *Locale) Record(g attempt.Gamemode, name, score string) string {
+	format, ok := l.records[g]
+	if !ok {
+		panic(fmt.Errorf("unknown gamemode %q", g))
+	}
+
+	return fmt.Sprintf(format, l.world(name), score)
+}
+
+func (l *Locale) Challenge(g attempt.Gamemode) string {
+	c, ok := l.challenges[g]
+	if !ok {
+		panic(fmt.Errorf("unknown gamemode %q", g))
+	}
+
+	return c
+}
+
+func (l *Locale) DidIt(name string) string { return l.didIt(name) }
+
+// Times formats how many times something was done.
+func (l *Locale) Times(n int) string { return l.times(n) }
+
+func (l *Locale) Wins(n int) string { return l.wins(n) }
+
+// Duration formats a duration given in milliseconds.
+func (l *Locale) Duration(ms float64) string {
+	dur := time.Duration(ms) * time.Millisecond
+	if dur > time.Second {
+		dur = dur.Round(time.Second / 100)
+	}
+
+	return l.duration(dur)
+}
+
+var all = []*Locale{English, German, Romanian}
+
+var matcher = func() language.Matcher {
+	tags := make([]language.Tag, 0, len(all))
+	for _, l := range all {
+		tags = append(tags, l.Tag)
+	}
+
+	return language.NewMatcher(tags)
+}()
+
+// Match returns the locale which best fits the given Accept-Language header value.
+// English is returned if no locale fits.
+func Match(acceptLanguage string) *Locale {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return English
+	}
+
+	_, i, conf := matcher.Match(tags...)
+	if conf == language.No {
+		return English
+	}
+
+	return all[i]
+}
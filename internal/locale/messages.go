@@ -0,0 +1,208 @@
+package locale
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"golang.org/x/text/language"
+)
+
+var English = &Locale{
+	Tag:         language.English,
+	OG:          "en_US",
+	Description: "Pop all the squares in the grid. Will you make it?",
+	Objective:   "Objective: pop all the squares in the grid. Will you make it?",
+	world: func(name string) string {
+		if name == "" {
+			name = "your friend"
+		}
+
+		// Names ending in s or z only take the apostrophe.
+		if strings.HasSuffix(name, "s") || strings.HasSuffix(name, "z") {
+			return fmt.Sprintf("You're in %s' world:", name)
+		}
+
+		return fmt.Sprintf("You're in %s's world:", name)
+	},
+	didIt: func(name string) string {
+		if name == "" {
+			name = "Your friend"
+		}
+
+		return name + " did it:"
+	},
+	times: func(n int) string {
+		if n == 1 {
+			return "1 time"
+		}
+
+		return fmt.Sprintf("%d times", n)
+	},
+	wins: func(n int) string {
+		if n == 1 {
+			return "1 win"
+		}
+
+		return fmt.Sprintf("%d wins", n)
+	},
+	records: map[attempt.Gamemode]string{
+		attempt.GamemodeRandom:      "%s can you pop all squares more times? They did it %s.",
+		attempt.GamemodeSameSquare:  "%s can you destroy the same squares faster? They did it in %s.",
+		attempt.GamemodePassthrough: "%s are your fingers the fastest? Pop all squares in %s to win!",
+		attempt.GamemodeOddOneOut:   "%s can you pop the odd square quicker? Finish in under %s to win!",
+	},
+	challenges: map[attempt.Gamemode]string{
+		attempt.GamemodeRandom:      "Can you win more than me?",
+		attempt.GamemodeSameSquare:  "Can you destroy the squares faster?",
+		attempt.GamemodePassthrough: "Can you be faster than me?",
+		attempt.GamemodeOddOneOut:   "Can you spot the odd one out faster?",
+	},
+	duration: time.Duration.String,
+}
+
+var German = &Locale{
+	Tag:         language.German,
+	OG:          "de_DE",
+	Description: "Lass alle Karos im Raster platzen. Schaffst du es?",
+	Objective:   "Ziel: Lass alle Karos im Raster platzen. Schaffst du es?",
+	world: func(name string) string {
+		if name == "" {
+			return "Du bist in der Welt deines Freundes:"
+		}
+
+		// Names ending in a sibilant take an apostrophe instead of the genitive s.
+		if last, _ := utf8.DecodeLastRuneInString(name); strings.ContainsRune("sßxzSXZ", last) {
+			return fmt.Sprintf("Du bist in %s' Welt:", name)
+		}
+
+		return fmt.Sprintf("Du bist in %ss Welt:", name)
+	},
+	didIt: func(name string) string {
+		if name == "" {
+			name = "Dein Freund"
+		}
+
+		return name + " hat es geschafft:"
+	},
+	times: func(n int) string {
+		if n == 1 {
+			return "einmal"
+		}
+
+		return fmt.Sprintf("%d-mal", n)
+	},
+	wins: func(n int) string {
+		if n == 1 {
+			return "1 Sieg"
+		}
+
+		return fmt.Sprintf("%d Siege", n)
+	},
+	records: map[attempt.Gamemode]string{
+		attempt.GamemodeRandom:      "%s Kannst du öfter alle Karos platzen lassen? Geschafft wurde es %s.",
+		attempt.GamemodeSameSquare:  "%s Zerstörst du die gleichen Karos schneller? Geschafft wurde es in %s.",
+		attempt.GamemodePassthrough: "%s Sind deine Finger die schnellsten? Lass alle Karos in %s platzen, um zu gewinnen!",
+		attempt.GamemodeOddOneOut:   "%s Findest du das andere Karo schneller? Schaffe es in unter %s, um zu gewinnen!",
+	},
+	challenges: map[attempt.Gamemode]string{
+		attempt.GamemodeRandom:      "Gewinnst du öfter als ich?",
+		attempt.GamemodeSameSquare:  "Zerstörst du die Karos schneller?",
+		attempt.GamemodePassthrough: "Bist du schneller als ich?",
+		attempt.GamemodeOddOneOut:   "Findest du den Ausreißer schneller?",
+	},
+	duration: func(d time.Duration) string { return decimalDuration(d, ",") },
+}
+
+var Romanian = &Locale{
+	Tag:         language.Romanian,
+	OG:          "ro_RO",
+	Description: "Sparge toate pătratele din grilă. Vei reuși?",
+	Objective:   "Obiectiv: sparge toate pătratele din grilă. Vei reuși?",
+	world: func(name string) string {
+		if name == "" {
+			return "Ești în lumea prietenului tău:"
+		}
+
+		return fmt.Sprintf("Ești în lumea lui %s:", name)
+	},
+	didIt: func(name string) string {
+		if name == "" {
+			name = "Prietenul tău"
+		}
+
+		return name + " a reușit:"
+	},
+	times: func(n int) string {
+		switch romanianPlural(n) {
+		case pluralOne:
+			return "o dată"
+		case pluralFew:
+			return fmt.Sprintf("de %d ori", n)
+		default:
+			return fmt.Sprintf("de %d de ori", n)
+		}
+	},
+	wins: func(n int) string {
+		switch romanianPlural(n) {
+		case pluralOne:
+			return "o victorie"
+		case pluralFew:
+			return fmt.Sprintf("%d victorii", n)
+		default:
+			return fmt.Sprintf("%d de victorii", n)
+		}
+	},
+	records: map[attempt.Gamemode]string{
+		attempt.GamemodeRandom:      "%s poți sparge toate pătratele de mai multe ori? A reușit %s.",
+		attempt.GamemodeSameSquare:  "%s poți distruge mai repede aceleași pătrate? A reușit în %s.",
+		attempt.GamemodePassthrough: "%s ai cele mai rapide degete? Sparge toate pătratele în %s ca să câștigi!",
+		attempt.GamemodeOddOneOut:   "%s găsești mai repede pătratul diferit? Termină în sub %s ca să câștigi!",
+	},
+	challenges: map[attempt.Gamemode]string{
+		attempt.GamemodeRandom:      "Poți câștiga mai des decât mine?",
+		attempt.GamemodeSameSquare:  "Poți distruge pătratele mai repede?",
+		attempt.GamemodePassthrough: "Poți fi mai rapid decât mine?",
+		attempt.GamemodeOddOneOut:   "Poți găsi mai repede pătratul diferit?",
+	},
+	duration: func(d time.Duration) string { return decimalDuration(d, ",") },
+}
+
+type pluralForm int
+
+const (
+	pluralOne pluralForm = iota
+	pluralFew
+	pluralOther
+)
+
+// romanianPlural follows the CLDR rules: numbers ending in 20 to 99 (and 100, 200...)
+// are joined to their noun with "de".
+func romanianPlural(n int) pluralForm {
+	switch rem := n % 100; {
+	case n == 1:
+		return pluralOne
+	case n == 0 || (rem >= 1 && rem <= 19):
+		return pluralFew
+	default:
+		return pluralOther
+	}
+}
+
+// decimalDuration formats durations with SI units, using sep as the decimal separator.
+func decimalDuration(d time.Duration, sep string) string {
+	if d < time.Second {
+		return fmt.Sprintf("%d ms", d.Milliseconds())
+	}
+
+	mins, secs := d/time.Minute, (d % time.Minute).Seconds()
+	s := strings.Replace(strconv.FormatFloat(secs, 'f', -1, 64), ".", sep, 1) + " s"
+	if mins > 0 {
+		return fmt.Sprintf("%d min %s", mins, s)
+	}
+
+	return s
+}
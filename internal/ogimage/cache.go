@@ -0,0 +1,69 @@
+package ogimage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tmaxmax/popthegrid/internal/locale"
+	"github.com/tmaxmax/popthegrid/internal/share"
+)
+
+// Cache keeps rendered images as files in Dir, named by the code of their record and their language.
+type Cache struct {
+	Dir string
+}
+
+// Path returns the path of the file holding the image of the record shared under code in the given locale,
+// rendering it first if the file is missing or holds the image of an older version of the record.
+func (c Cache) Path(code share.Code, rec share.Record, l *locale.Locale) (string, error) {
+	// Codes are case sensitive, file systems might not be.
+	prefix := hex.EncodeToString([]byte(code)) + "-" + l.Tag.String() + "-"
+	path := filepath.Join(c.Dir, prefix+Version(rec)+".jpg")
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("create cache dir: %w", err)
+	}
+
+	// Concurrent renders of the same image don't see each other's partial files.
+	f, err := os.CreateTemp(c.Dir, prefix+"*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	err = JPEG(f, code, rec, l)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("render: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", fmt.Errorf("save file: %w", err)
+	}
+
+	if stale, err := filepath.Glob(filepath.Join(c.Dir, prefix+"*.jpg")); err == nil {
+		for _, p := range stale {
+			if p != path {
+				_ = os.Remove(p)
+			}
+		}
+	}
+
+	return path, nil
+}
+
+// Remove deletes the images of the record shared under code, in all languages.
+func (c Cache) Remove(code share.Code) {
+	paths, _ := filepath.Glob(filepath.Join(c.Dir, hex.EncodeToString([]byte(code))+"-*.jpg"))
+	for _, p := range paths {
+		_ = os.Remove(p)
+	}
+}
@@ -0,0 +1,146 @@
+// Package ogimage renders the Open Graph images of shared records.
+package ogimage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"math/rand/v2"
+
+	"github.com/tmaxmax/popthegrid/internal/locale"
+	"github.com/tmaxmax/popthegrid/internal/share"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	Width  = 1200
+	Height = 627
+)
+
+// revision changes whenever the layout of the images changes, so older renders are replaced.
+const revision = 1
+
+var (
+	bold    = parseFont(gobold.TTF)
+	regular = parseFont(goregular.TTF)
+)
+
+func parseFont(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// newFace creates a font face. Faces can't be shared between goroutines.
+func newFace(f *opentype.Font, size float64) font.Face {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		panic(err)
+	}
+
+	return face
+}
+
+// Version identifies the image of the record: it changes when the record's image would.
+func Version(rec share.Record) string {
+	b, _ := json.Marshal(struct {
+		Revision int
+		Name     string
+		Gamemode string
+		Theme    string
+		Data     share.RecordData
+	}{revision, rec.Name, string(rec.Gamemode), string(rec.Theme), rec.Data})
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:4])
+}
+
+const (
+	gridSize  = 4
+	gridX     = 110
+	gridY     = 163
+	cellSize  = 75
+	cellInset = 4
+	textX     = 470
+	textWidth = Width - textX - 60
+)
+
+// JPEG writes the image of the record shared under the given code, in the given locale.
+func JPEG(w io.Writer, code share.Code, rec share.Record, l *locale.Locale) error {
+	if err := rec.Theme.Validate(); err != nil {
+		return err
+	}
+
+	p := rec.Theme.Palette()
+
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(p.Background), image.Point{}, draw.Src)
+
+	// The colors of the grid are picked by the code, so every link has its own.
+	h := fnv.New64a()
+	h.Write([]byte(code))
+	rnd := rand.New(rand.NewPCG(h.Sum64(), revision))
+
+	for row := range gridSize {
+		for col := range gridSize {
+			c := p.Squares[rnd.IntN(len(p.Squares))]
+			x, y := gridX+col*cellSize, gridY+row*cellSize
+			fillRoundedRect(img, image.Rect(x+cellInset, y+cellInset, x+cellSize-cellInset, y+cellSize-cellInset), 10, c)
+		}
+	}
+
+	body := newFace(regular, 40)
+	drawText(img, newFace(bold, 84), p.Heading, 235, "Pop the grid!")
+	drawText(img, body, p.Body, 320, l.DidIt(rec.Name))
+	drawText(img, newFace(bold, 64), p.Heading, 395, rec.Score(l))
+	drawText(img, body, p.Body, 460, l.Challenge(rec.Gamemode))
+
+	if err := jpeg.Encode(w, img, &jpeg.Options{Quality: 90}); err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	return nil
+}
+
+// drawText draws a line of text with the given baseline, shortening it to fit the text area.
+func drawText(img draw.Image, face font.Face, c color.Color, y int, s string) {
+	d := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face, Dot: fixed.P(textX, y)}
+
+	maxWidth := fixed.I(textWidth)
+	if d.MeasureString(s) > maxWidth {
+		r := []rune(s)
+		for len(r) > 0 && d.MeasureString(string(r)+"…") > maxWidth {
+			r = r[:len(r)-1]
+		}
+		s = string(r) + "…"
+	}
+
+	d.DrawString(s)
+}
+
+func fillRoundedRect(img *image.RGBA, r image.Rectangle, radius int, c color.RGBA) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Distance from the nearest corner's center, if the pixel is in a corner.
+			dx := max(r.Min.X+radius-x, x-(r.Max.X-1-radius), 0)
+			dy := max(r.Min.Y+radius-y, y-(r.Max.Y-1-radius), 0)
+			if dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
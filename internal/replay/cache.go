@@ -0,0 +1,70 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/uuid"
+)
+
+// Cache keeps rendered replays as files in Dir, named by the ID of their attempt.
+// Rendering takes a lot of CPU and memory, so only a few replays are rendered at once.
+type Cache struct {
+	Dir     string
+	renders chan struct{}
+}
+
+// NewCache returns a cache in dir which renders at most maxRenders replays at once.
+func NewCache(dir string, maxRenders int) *Cache {
+	return &Cache{Dir: dir, renders: make(chan struct{}, maxRenders)}
+}
+
+// Path returns the path of the file holding the replay of the attempt with the given ID,
+// rendering it with render first if the file is missing. Replays never change, as traces don't.
+func (c *Cache) Path(ctx context.Context, id uuid.UUID, render func(w io.Writer) error) (string, error) {
+	path := filepath.Join(c.Dir, id.String()+".gif")
+
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	select {
+	case c.renders <- struct{}{}:
+		defer func() { <-c.renders }()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	// The replay might have been rendered while waiting.
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("create cache dir: %w", err)
+	}
+
+	// Concurrent renders of the same replay don't see each other's partial files.
+	f, err := os.CreateTemp(c.Dir, id.String()+"-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	err = render(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("render: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", fmt.Errorf("save file: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,281 @@
+// Package replay renders animated replays of attempts from their traces.
+package replay
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+	"github.com/tmaxmax/popthegrid/internal/verify"
+)
+
+// Options configure the rendered replay.
+type Options struct {
+	// CellSize is the side length of a grid cell, in pixels. Defaults to 32.
+	CellSize int
+	// MaxFrames limits the length of the replay: longer games are sped up to fit.
+	// Defaults to 600, which is 30 seconds of real time.
+	MaxFrames int
+}
+
+const (
+	frameInterval = 50 * time.Millisecond
+	// shiftDuration is how long squares take to move to their new cell after a removal.
+	shiftDuration = 500 * time.Millisecond
+	// trailDuration is how long pointer positions stay visible.
+	trailDuration = 300 * time.Millisecond
+	// lead and tail are shown before the first and after the last removal.
+	lead = 500 * time.Millisecond
+	tail = time.Second
+	// endDelay is how long the last frame is shown before the replay loops, in 100ths of a second.
+	endDelay = 200
+)
+
+// Indices of the colors in a frame's palette.
+const (
+	colorBackground uint8 = iota
+	colorUnknown
+	colorPointer
+	colorTrail
+	colorSquare
+)
+
+// GIF writes an animated replay of the attempt's game, as recorded by the trace.
+func GIF(w io.Writer, att *attempt.Attempt, tr *trace.Trace, opts Options) error {
+	g, err := newGame(att, tr)
+	if err != nil {
+		return err
+	}
+
+	return gif.EncodeAll(w, g.render(cmp.Or(opts.CellSize, 32), cmp.Or(opts.MaxFrames, 600)))
+}
+
+type point struct{ x, y float64 }
+
+func lerp(a, b point, p float64) point {
+	return point{a.x + (b.x-a.x)*p, a.y + (b.y-a.y)*p}
+}
+
+// square is a square of the grid, moving towards its cell.
+type square struct {
+	from  point
+	start time.Duration
+}
+
+type removal struct {
+	t     time.Duration
+	index int
+}
+
+type game struct {
+	theme      attempt.Theme
+	numSquares int
+	grids      []trace.EventGridResize
+	removals   []removal
+	// boards are the colors of the squares, in layout order, before the first removal and after each one.
+	boards   [][]uint8
+	pointers []trace.PointerEvent
+}
+
+// newGame finds which square each removal refers to and replays the attempt's
+// random stream, so squares are drawn in the colors they had at every moment.
+// Grids must have the attempt's number of squares, which bounds the size of the replay.
+func newGame(att *attempt.Attempt, tr *trace.Trace) (*game, error) {
+	numSquares := att.NumSquares
+	g := &game{theme: tr.Theme(), numSquares: numSquares, pointers: tr.PointerEvents}
+	if g.theme == "" {
+		g.theme = attempt.ThemeCandy
+	}
+
+	boards, err := verify.Boards(att, tr)
+	if err != nil {
+		return nil, fmt.Errorf("replay boards: %w", err)
+	}
+
+	for _, b := range boards {
+		colors := make([]uint8, len(b))
+		for i, c := range b {
+			colors[i] = colorUnknown
+			if c >= 0 {
+				colors[i] = colorSquare + uint8(c)
+			}
+		}
+
+		g.boards = append(g.boards, colors)
+	}
+
+	for _, e := range tr.Events {
+		switch e := e.(type) {
+		case trace.EventGridResize:
+			if e.Cols <= 0 || e.SideLength <= 0 {
+				return nil, errors.New("invalid grid layout")
+			} else if e.NumSquares != numSquares {
+				return nil, fmt.Errorf("grid has %d squares, expected %d", e.NumSquares, numSquares)
+			}
+
+			// More columns than squares would only widen the replay.
+			e.Cols = min(e.Cols, numSquares)
+			g.grids = append(g.grids, e)
+		case trace.GameEventRemoveSquare:
+			if len(g.grids) == 0 {
+				return nil, errors.New("square removed before grid layout was recorded")
+			}
+
+			cols := g.grids[len(g.grids)-1].Cols
+			i := e.Square.Row*cols + e.Square.Col
+			if e.Square.Row < 0 || e.Square.Col < 0 || e.Square.Col >= cols || i >= numSquares-len(g.removals) {
+				return nil, fmt.Errorf("square (%d, %d) is not in the grid", e.Square.Row, e.Square.Col)
+			}
+
+			g.removals = append(g.removals, removal{t: e.T, index: i})
+		}
+	}
+
+	if len(g.removals) == 0 {
+		return nil, errors.New("no squares removed")
+	} else if err := g.theme.Validate(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *game) palette() color.Palette {
+	p := g.theme.Palette()
+
+	pal := color.Palette{p.Background, mix(p.Background, p.Body), p.Heading, mix(p.Background, p.Heading)}
+	for _, c := range p.Squares {
+		pal = append(pal, c)
+	}
+
+	return pal
+}
+
+func mix(a, b color.RGBA) color.RGBA {
+	return color.RGBA{R: uint8((int(a.R) + int(b.R)) / 2), G: uint8((int(a.G) + int(b.G)) / 2), B: uint8((int(a.B) + int(b.B)) / 2), A: 0xff}
+}
+
+func (g *game) render(cell, maxFrames int) *gif.GIF {
+	var cols, rows int
+	for _, grid := range g.grids {
+		cols = max(cols, grid.Cols)
+		rows = max(rows, (g.numSquares+grid.Cols-1)/grid.Cols)
+	}
+
+	pad := cell / 2
+	bounds := image.Rect(0, 0, cols*cell+2*pad, rows*cell+2*pad)
+	pal := g.palette()
+
+	start := g.removals[0].t - lead
+	end := g.removals[len(g.removals)-1].t + tail
+	step := max(frameInterval, (end-start)/time.Duration(maxFrames))
+
+	board := make([]int, g.numSquares)
+	squares := make([]square, g.numSquares)
+	for i := range board {
+		board[i] = i
+		squares[i] = square{start: start - shiftDuration}
+	}
+
+	grid := g.grids[0]
+	gi, ri, pi := 0, 0, 0
+	anim := &gif.GIF{}
+
+	for t := start; ; t += step {
+		t = min(t, end)
+
+		for ; gi < len(g.grids) && g.grids[gi].T <= t; gi++ {
+			// The grid is laid out again instantly, so squares jump to their new cells.
+			grid = g.grids[gi]
+			for i, id := range board {
+				squares[id].from = cellOf(i, grid.Cols)
+				squares[id].start = t - shiftDuration
+			}
+		}
+
+		for ; ri < len(g.removals) && g.removals[ri].t <= t; ri++ {
+			r := g.removals[ri]
+			board = append(board[:r.index], board[r.index+1:]...)
+			for i := r.index; i < len(board); i++ {
+				id := board[i]
+				squares[id].from = squares[id].position(i+1, grid.Cols, r.t)
+				squares[id].start = r.t
+			}
+		}
+
+		for pi < len(g.pointers) && g.pointers[pi].T <= t {
+			pi++
+		}
+
+		img := image.NewPaletted(bounds, pal)
+		colors := g.boards[ri]
+		for i, id := range board {
+			p := squares[id].position(i, grid.Cols, t)
+			fillRect(img, pad+int(math.Round(p.x*float64(cell))), pad+int(math.Round(p.y*float64(cell))), cell, cell/10, colors[i])
+		}
+
+		// Pointer positions are mapped to the grid in effect when they're drawn.
+		for j := pi - 1; j >= 0 && t-g.pointers[j].T < trailDuration; j-- {
+			pe := g.pointers[j]
+			x := float64(pad) + (float64(pe.Position.X)-grid.Anchor.X)/grid.SideLength*float64(cell)
+			y := float64(pad) + (float64(pe.Position.Y)-grid.Anchor.Y)/grid.SideLength*float64(cell)
+
+			c, radius := colorTrail, cell/10
+			if j == pi-1 {
+				c, radius = colorPointer, cell/6
+			}
+
+			fillCircle(img, int(math.Round(x)), int(math.Round(y)), radius, c)
+		}
+
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, int(frameInterval/(10*time.Millisecond)))
+
+		if t == end {
+			break
+		}
+	}
+
+	anim.Delay[len(anim.Delay)-1] = endDelay
+
+	return anim
+}
+
+// position returns the top left corner of the square, in cells, moving towards the cell at index i.
+func (s square) position(i, cols int, t time.Duration) point {
+	p := min(float64(t-s.start)/float64(shiftDuration), 1)
+	return lerp(s.from, cellOf(i, cols), p)
+}
+
+func cellOf(i, cols int) point {
+	return point{float64(i % cols), float64(i / cols)}
+}
+
+// fillRect draws a square of the given size with its top left corner at (x, y), inset on all sides.
+func fillRect(img *image.Paletted, x, y, size, inset int, c uint8) {
+	r := image.Rect(x+inset, y+inset, x+size-inset, y+size-inset).Intersect(img.Rect)
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			img.SetColorIndex(px, py, c)
+		}
+	}
+}
+
+func fillCircle(img *image.Paletted, cx, cy, radius int, c uint8) {
+	r := image.Rect(cx-radius, cy-radius, cx+radius+1, cy+radius+1).Intersect(img.Rect)
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			if dx, dy := px-cx, py-cy; dx*dx+dy*dy <= radius*radius {
+				img.SetColorIndex(px, py, c)
+			}
+		}
+	}
+}
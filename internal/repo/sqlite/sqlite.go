@@ -1,67 +1,201 @@
 package sqlite
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/tmaxmax/popthegrid/internal/attempt"
 	"github.com/tmaxmax/popthegrid/internal/handler"
+	"github.com/tmaxmax/popthegrid/internal/leaderboard"
 	"github.com/tmaxmax/popthegrid/internal/share"
 	"github.com/tmaxmax/popthegrid/internal/trace"
+	"github.com/tmaxmax/popthegrid/internal/verify"
 	"modernc.org/sqlite"
 	sqlite3 "modernc.org/sqlite/lib"
 )
 
 type Repository struct {
 	DB *sql.DB
+	// Logger reports how crowded the space of share codes is. Defaults to slog.Default.
+	Logger *slog.Logger
+
+	codes    share.CodeGenerator
+	fitCodes sync.Once
+}
+
+func (r *Repository) logger() *slog.Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+
+	return slog.Default()
 }
 
+// Get returns the record shared under the code. Records of attempts whose traces were found
+// to contradict them after being shared are gone, as their data can't be trusted. Those which
+// only failed heuristics remain, as the heuristics may reject genuine attempts.
 func (r *Repository) Get(ctx context.Context, code share.Code) (share.Record, error) {
 	const query = `select
 	links.name, attempts.gamemode, links.theme, attempts.started_at, links.data,
-	case when attempts.verification = 'UNKNOWN' then null else attempts.id end
+	case when attempts.verification = 'UNKNOWN' then null else attempts.id end,
+	links.session_id, links.player_id, ` + linkGone + `
+		or (attempts.verification = 'INVALID' and not attempts.verification_heuristic)
 from links
 	join attempts on attempts.id = links.attempt_id
 where code = $1`
 
 	var rec share.Record
+	var gone bool
 
-	row := r.DB.QueryRowContext(ctx, query, string(code))
-	err := row.Scan(&rec.Name, &rec.Gamemode, &rec.Theme, &rec.When, (*recordData)(&rec.Data), &rec.AttemptID)
+	row := r.DB.QueryRowContext(ctx, query, string(code), time.Now().UTC().Truncate(0))
+	err := row.Scan(&rec.Name, &rec.Gamemode, &rec.Theme, &rec.When, (*recordData)(&rec.Data), &rec.AttemptID, &rec.SessionID, &rec.PlayerID, &gone)
 	if err == sql.ErrNoRows {
 		return share.Record{}, createError(handler.ErrorNotFound, err)
 	} else if err != nil {
 		return share.Record{}, createError(handler.ErrorInternal, err)
+	} else if gone {
+		return share.Record{}, createError(handler.ErrorGone, fmt.Errorf("link %s", code))
 	}
 
 	return rec, nil
 }
 
+// linkGone tells whether the link was deleted or expired before $2.
+const linkGone = `(links.deleted_at is not null or coalesce(links.expires_at <= $2, false))`
+
+func (r *Repository) Edit(ctx context.Context, code share.Code, edit share.Edit) error {
+	var expiresAt *time.Time
+	if edit.ExpiresAt != nil {
+		// Timestamps are compared as text, so they are all stored in UTC.
+		t := edit.ExpiresAt.UTC().Truncate(0)
+		expiresAt = &t
+	}
+
+	const query = `update links set name = coalesce($3, name), expires_at = coalesce($4, expires_at)
+where code = $1 and not ` + linkGone
+
+	if err := r.updateLink(ctx, query, code, edit.Name, expiresAt); err != nil {
+		return err
+	}
+
+	if edit.Name != nil {
+		const query = `delete from name_moderation where code = $1 and status = 'PENDING'`
+
+		if _, err := r.DB.ExecContext(ctx, query, code); err != nil {
+			return createError(handler.ErrorInternal, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) Delete(ctx context.Context, code share.Code) error {
+	// The name is the only data of the link which isn't derived from its attempt.
+	const query = `update links set name = '', deleted_at = $2 where code = $1 and not ` + linkGone
+
+	return r.updateLink(ctx, query, code)
+}
+
+// updateLink runs the query with the link's code and the current time as the first arguments,
+// reporting whether the link wasn't found or is gone if nothing was updated.
+func (r *Repository) updateLink(ctx context.Context, query string, code share.Code, args ...any) error {
+	now := time.Now().UTC().Truncate(0)
+
+	res, err := r.DB.ExecContext(ctx, query, append([]any{code, now}, args...)...)
+	if err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return createError(handler.ErrorInternal, err)
+	} else if n > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.DB.QueryRowContext(ctx, "select exists(select 1 from links where code = $1)", code).Scan(&exists); err != nil {
+		return createError(handler.ErrorInternal, err)
+	} else if exists {
+		return createError(handler.ErrorGone, fmt.Errorf("link %s", code))
+	}
+
+	return createError(handler.ErrorNotFound, fmt.Errorf("link %s", code))
+}
+
+// maxCodeTries is the number of codes of a length tried for a record before the codes are lengthened.
+const maxCodeTries = 10
+
 func (r *Repository) Save(ctx context.Context, record share.Record) (share.Code, error) {
-	now := time.Now().Truncate(0)
+	now := time.Now().UTC().Truncate(0)
+	l := r.logger()
+
+	// Codes generated before a restart are accounted for by their count.
+	r.fitCodes.Do(func() {
+		var taken int
+		if err := r.DB.QueryRowContext(ctx, `select count(*) from links where not vanity`).Scan(&taken); err != nil {
+			l.ErrorContext(ctx, "count share codes", "err", err)
+		}
 
-	for range 10 {
-		code := share.NewCode()
+		r.codes.Fit(taken)
+	})
 
-		ok, err := r.trySaveWithCode(ctx, record, code, now)
+	// tries counts the collisions at the current length.
+	for collisions, tries := 0, 0; ; {
+		code := r.codes.New()
+
+		ok, err := r.trySaveWithCode(ctx, record, code, false, now)
+		if err != nil {
+			return "", err
+		}
+
+		grew := r.codes.Observe(!ok)
 		if ok {
+			if collisions > 0 {
+				l.InfoContext(ctx, "share code collisions", "collisions", collisions, "length", len(code))
+			}
+
 			return code, nil
-		} else if err != nil {
-			return "", err
 		}
+
+		collisions++
+		tries++
+		if !grew && tries == maxCodeTries {
+			if grew = r.codes.Grow(); !grew {
+				return "", createError(handler.ErrorInternal, errors.New("couldn't create unique code"))
+			}
+		}
+
+		if grew {
+			tries = 0
+			l.WarnContext(ctx, "share codes lengthened", "collisions", collisions, "length", r.codes.Length())
+		}
+	}
+}
+
+func (r *Repository) SaveAs(ctx context.Context, record share.Record, code share.Code) error {
+	ok, err := r.trySaveWithCode(ctx, record, code, true, time.Now().UTC().Truncate(0))
+	if err != nil {
+		return err
+	} else if !ok {
+		return createError(handler.ErrorCodeTaken, fmt.Errorf("code %s", code))
 	}
 
-	return "", createError(handler.ErrorInternal, errors.New("couldn't create unique code"))
+	return nil
 }
 
-func (r *Repository) trySaveWithCode(ctx context.Context, record share.Record, code share.Code, now time.Time) (bool, error) {
-	const query = `insert into links (code, name, theme, attempt_id, data, created_at) values ($1, $2, $3, $4, $5, $6)`
+// trySaveWithCode saves the record under the code. It reports false without an error if the code is taken.
+func (r *Repository) trySaveWithCode(ctx context.Context, record share.Record, code share.Code, vanity bool, now time.Time) (bool, error) {
+	const query = `insert into links (code, name, theme, attempt_id, data, created_at, session_id, player_id, vanity) values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -69,25 +203,30 @@ func (r *Repository) trySaveWithCode(ctx context.Context, record share.Record, c
 	}
 	defer tx.Rollback()
 
+	// Vanity codes would be mistaken for any code which only differs by case, while
+	// random codes only for vanity ones: random codes of different case are told apart.
+	const taken = `select exists(select 1 from links where lower(code) = lower($1) and (vanity or $2))`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, taken, code, vanity).Scan(&exists); err != nil {
+		return false, createError(handler.ErrorInternal, err)
+	} else if exists {
+		return false, nil
+	}
+
 	if !record.AttemptID.Valid {
 		record.AttemptID.UUID, err = attemptFromRecord(ctx, tx, record, now)
 		if err != nil {
 			return false, fmt.Errorf("create attempt: %w", err)
 		}
 	} else {
-		var kind attempt.Kind
-		if err := tx.QueryRowContext(ctx, "select kind from attempts where verification <> 'UNKNOWN' and id = $1", record.AttemptID.UUID).Scan(&kind); err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return false, createError(handler.ErrorNotFound, err)
-			}
-		}
-
-		if kind != attempt.Win {
-			return false, createError(handler.ErrorNotWin, nil)
+		record.Data, err = attemptRecordData(ctx, tx, record)
+		if err != nil {
+			return false, err
 		}
 	}
 
-	_, err = tx.ExecContext(ctx, query, code, record.Name, record.Theme, record.AttemptID.UUID, recordData(record.Data), now)
+	_, err = tx.ExecContext(ctx, query, code, record.Name, record.Theme, record.AttemptID.UUID, recordData(record.Data), now, record.SessionID, record.PlayerID, vanity)
 	if err == nil {
 		if err := tx.Commit(); err != nil {
 			return false, createError(handler.ErrorInternal, err)
@@ -104,6 +243,11 @@ func (r *Repository) trySaveWithCode(ctx context.Context, record share.Record, c
 		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
 			return false, createError(handler.ErrorNotFound, err)
 		case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			// Another vanity code differing from this one only by case was saved concurrently.
+			if vanity && strings.Contains(sqerr.Error(), "links_vanity") {
+				return false, nil
+			}
+
 			return false, createError(handler.ErrorAlreadySubmitted, err)
 		}
 	}
@@ -111,22 +255,526 @@ func (r *Repository) trySaveWithCode(ctx context.Context, record share.Record, c
 	return false, createError(handler.ErrorInternal, err)
 }
 
-func (r *Repository) Submit(ctx context.Context, att *attempt.Attempt, tr *trace.Trace) (uuid.UUID, error) {
+func (r *Repository) HoldName(ctx context.Context, code share.Code, name string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `delete from name_moderation where code = $1 and status = 'PENDING'`, code); err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	const query = `insert into name_moderation (code, name, created_at) values ($1, $2, $3)`
+
+	_, err = tx.ExecContext(ctx, query, code, name, time.Now().UTC().Truncate(0))
+
+	var sqerr *sqlite.Error
+	if errors.As(err, &sqerr) && sqerr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return createError(handler.ErrorNotFound, err)
+	} else if err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	return nil
+}
+
+const maxHeldNames = 100
+
+func (r *Repository) HeldNames(ctx context.Context) ([]share.HeldName, error) {
+	const query = `select name_moderation.id, name_moderation.code, name_moderation.name, name_moderation.created_at
+from name_moderation
+	join links on links.code = name_moderation.code
+where name_moderation.status = 'PENDING' and not ` + linkGone + `
+order by name_moderation.created_at
+limit $1`
+
+	rows, err := r.DB.QueryContext(ctx, query, maxHeldNames, time.Now().UTC().Truncate(0))
+	if err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+	defer rows.Close()
+
+	var names []share.HeldName
+	for rows.Next() {
+		var n share.HeldName
+		if err := rows.Scan(&n.ID, &n.Code, &n.Name, &n.CreatedAt); err != nil {
+			return nil, createError(handler.ErrorInternal, err)
+		}
+
+		names = append(names, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+
+	return names, nil
+}
+
+func (r *Repository) DecideName(ctx context.Context, id int64, approve bool) error {
+	now := time.Now().UTC().Truncate(0)
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+	defer tx.Rollback()
+
+	var code share.Code
+	var name string
+
+	err = tx.QueryRowContext(ctx, `select code, name from name_moderation where id = $1 and status = 'PENDING'`, id).Scan(&code, &name)
+	if err == sql.ErrNoRows {
+		return createError(handler.ErrorNotFound, fmt.Errorf("held name %d", id))
+	} else if err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	status := "REJECTED"
+	if approve {
+		status = "APPROVED"
+
+		const query = `update links set name = $3 where code = $1 and not ` + linkGone
+
+		res, err := tx.ExecContext(ctx, query, code, now, name)
+		if err != nil {
+			return createError(handler.ErrorInternal, err)
+		} else if n, err := res.RowsAffected(); err != nil {
+			return createError(handler.ErrorInternal, err)
+		} else if n == 0 {
+			return createError(handler.ErrorGone, fmt.Errorf("link %s", code))
+		}
+	}
+
+	const query = `update name_moderation set status = $2, decided_at = $3 where id = $1`
+
+	if _, err := tx.ExecContext(ctx, query, id, status, now); err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Visit(ctx context.Context, code share.Code, sessionID uuid.NullUUID) error {
+	const query = `insert into link_visits (code, session_id, opens, created_at, updated_at) values ($1, $2, 1, $3, $3)
+on conflict (code, coalesce(session_id, '')) do update set opens = opens + 1, updated_at = excluded.updated_at`
+
+	_, err := r.DB.ExecContext(ctx, query, code, sessionID, time.Now().UTC().Truncate(0))
+
+	var sqerr *sqlite.Error
+	if errors.As(err, &sqerr) && sqerr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return createError(handler.ErrorNotFound, err)
+	} else if err != nil {
+		return createError(handler.ErrorInternal, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Stats(ctx context.Context, code share.Code) (share.Stats, error) {
+	const query = `select
+	coalesce(sum(opens), 0), count(distinct session_id), coalesce(sum(attempts), 0)
+from link_visits
+where code = $1`
+
+	var s share.Stats
+
+	err := r.DB.QueryRowContext(ctx, query, code).Scan(&s.Opens, &s.UniqueSessions, &s.ChallengeAttempts)
+	if err != nil {
+		return share.Stats{}, createError(handler.ErrorInternal, err)
+	}
+
+	return s, nil
+}
+
+// challengeWins selects the verified wins against the record ($1), except the ones of its creator.
+// Attempts and links saved by sessions without a player are attributed to the session instead.
+const challengeWins = `select coalesce(attempts.player_id, attempts.session_id) as player, attempts.duration_ms, attempts.started_at
+	from attempts
+		join links on links.code = attempts.challenge
+	where attempts.challenge = $1 and attempts.verification = 'VALID' and attempts.kind = 'WIN'
+		and coalesce(attempts.player_id, attempts.session_id) is not coalesce(links.player_id, links.session_id)`
+
+// challengerName is the name of the challenger's latest shared record which is still up at $3.
+const challengerName = `coalesce((
+	select name from links
+	where coalesce(links.player_id, links.session_id) = wins.player
+		and links.deleted_at is null and coalesce(links.expires_at > $3, true)
+	order by created_at desc limit 1
+), '')`
+
+const challengersFastest = `with wins as (
+	select *, row_number() over (partition by player order by duration_ms, started_at) as n
+	from (` + challengeWins + `)
+)
+select ` + challengerName + `, wins.duration_ms, 0, wins.started_at
+from wins
+where wins.n = 1
+order by wins.duration_ms, wins.started_at
+limit $2`
+
+const challengersMostWins = `with wins as (
+	select *,
+		count(*) over (partition by player) as num_wins,
+		row_number() over (partition by player order by started_at desc) as n
+	from (` + challengeWins + `)
+)
+select ` + challengerName + `, 0, wins.num_wins, wins.started_at
+from wins
+where wins.n = 1
+order by wins.num_wins desc, wins.started_at
+limit $2`
+
+const maxChallengers = 50
+
+func (r *Repository) Challengers(ctx context.Context, code share.Code, gamemode attempt.Gamemode) ([]share.Challenger, error) {
+	query := challengersFastest
+	if leaderboard.RanksWins(gamemode) {
+		query = challengersMostWins
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, code, maxChallengers, time.Now().UTC().Truncate(0))
+	if err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+	defer rows.Close()
+
+	var challengers []share.Challenger
+	for rows.Next() {
+		var c share.Challenger
+		if err := rows.Scan(&c.Name, &c.Data.FastestWinDuration, &c.Data.NumWins, &c.When); err != nil {
+			return nil, createError(handler.ErrorInternal, err)
+		}
+
+		challengers = append(challengers, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+
+	return challengers, nil
+}
+
+func (r *Repository) Submit(ctx context.Context, sub handler.Submission) (uuid.UUID, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	if sub.IdempotencyKey != "" {
+		id, err := submitted(ctx, tx, sub)
+		if err != nil || id != uuid.Nil {
+			return id, err
+		}
+	}
+
 	id, err := uuid.NewV4()
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("gen id: %w", err)
 	}
 
-	const query = `insert into attempts (id, gamemode, started_at, kind, num_squares, duration_ms, rand_state, trace, created_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err = r.DB.ExecContext(ctx, query, id, att.Gamemode, att.StartedAt, att.Kind, att.NumSquares, att.DurationMs, randState(att.RandState), tr, time.Now().Truncate(0))
-	if err != nil {
-		// TODO: handle particular error cases (ID conflict).
+	att, res := sub.Attempt, sub.Result
+
+	const query = `insert into attempts (
+	id, gamemode, started_at, kind, num_squares, duration_ms, rand_state, trace, created_at,
+	verification, verification_reason, verification_heuristic, session_id, idempotency_key, payload_hash, player_id, theme
+) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
+	_, err = tx.ExecContext(
+		ctx, query,
+		id, att.Gamemode, att.StartedAt, att.Kind, att.NumSquares, att.DurationMs, randState(att.RandState), sub.Trace, time.Now().UTC().Truncate(0),
+		res.Verification, nullString(res.Reason), res.Heuristic, sub.SessionID, nullString(sub.IdempotencyKey), sub.PayloadHash,
+		sub.PlayerID, nullString(string(sub.Trace.Theme())),
+	)
+
+	var sqerr *sqlite.Error
+	if errors.As(err, &sqerr) && sqerr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		// A retry of the submission was saved after this transaction started.
+		return uuid.Nil, createError(handler.ErrorKeyInProgress, err)
+	} else if err != nil {
 		return uuid.Nil, fmt.Errorf("insert: %w", err)
 	}
 
+	if att.Gamemode.UsesRand() && res.Verification != attempt.VerificationInvalid {
+		if err := consumeRand(ctx, tx, id, att.RandState, res.RandEnd); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if sub.Challenge != "" {
+		if err := challenge(ctx, tx, id, sub); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("commit: %w", err)
+	}
+
 	return id, nil
 }
 
+func (r *Repository) GetAttempt(ctx context.Context, id uuid.UUID) (attempt.Summary, error) {
+	const query = `select id, gamemode, kind, duration_ms, started_at, created_at, num_squares, session_id, verification, verification_reason, rand_state
+from attempts
+where verification <> 'UNKNOWN' and id = $1`
+
+	var s attempt.Summary
+	var reason sql.NullString
+
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&s.ID, &s.Gamemode, &s.Kind, &s.DurationMs, &s.StartedAt, &s.CreatedAt, &s.NumSquares, &s.SessionID, &s.Verification, &reason, (*randState)(&s.RandState))
+	if err == sql.ErrNoRows {
+		return attempt.Summary{}, createError(handler.ErrorNotFound, err)
+	} else if err != nil {
+		return attempt.Summary{}, createError(handler.ErrorInternal, err)
+	}
+
+	s.VerificationReason = reason.String
+
+	return s, nil
+}
+
+func (r *Repository) GetTrace(ctx context.Context, id uuid.UUID) (*trace.Trace, error) {
+	var tr trace.Trace
+
+	err := r.DB.QueryRowContext(ctx, "select trace from attempts where verification <> 'UNKNOWN' and id = $1", id).Scan(&tr)
+	if err == sql.ErrNoRows {
+		return nil, createError(handler.ErrorNotFound, err)
+	} else if err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+
+	return &tr, nil
+}
+
+// leaderboardPlayers selects the players who shared a record of the gamemode ($2),
+// with the name and code of their latest record.
+const leaderboardPlayers = `players as (
+	select links.player_id, links.name, links.code,
+		row_number() over (partition by links.player_id order by links.created_at desc) as n
+	from links
+		join attempts on attempts.id = links.attempt_id
+	where links.player_id is not null and attempts.gamemode = $2
+		and links.deleted_at is null and coalesce(links.expires_at > $6, true)
+)`
+
+// leaderboardWins selects the verified wins in the theme ($1) and gamemode ($2) submitted since $3.
+// Wins of sessions without a player count as their session's, or as their own without a session.
+const leaderboardWins = `select coalesce(player_id, session_id, id) as player, duration_ms, started_at
+	from attempts
+	where verification = 'VALID' and kind = 'WIN' and gamemode = $2 and theme = $1 and created_at >= $3`
+
+const leaderboardFastest = `with ` + leaderboardPlayers + `, wins as (
+	select player, duration_ms, started_at,
+		row_number() over (partition by player order by duration_ms, started_at) as n
+	from (` + leaderboardWins + `)
+)
+select rank() over (order by wins.duration_ms), coalesce(players.name, ''), coalesce(players.code, ''), wins.duration_ms, 0, wins.started_at
+from wins
+	left join players on players.player_id = wins.player and players.n = 1
+where wins.n = 1
+order by wins.duration_ms, wins.started_at
+limit $4 offset $5`
+
+const leaderboardMostWins = `with ` + leaderboardPlayers + `, wins as (
+	select player, started_at,
+		count(*) over (partition by player) as num_wins,
+		row_number() over (partition by player order by started_at desc) as n
+	from (` + leaderboardWins + `)
+)
+select rank() over (order by wins.num_wins desc), coalesce(players.name, ''), coalesce(players.code, ''), 0, wins.num_wins, wins.started_at
+from wins
+	left join players on players.player_id = wins.player and players.n = 1
+where wins.n = 1
+order by wins.num_wins desc, wins.started_at
+limit $4 offset $5`
+
+func (r *Repository) Leaderboard(ctx context.Context, q leaderboard.Query) ([]leaderboard.Entry, error) {
+	query := leaderboardFastest
+	if leaderboard.RanksWins(q.Gamemode) {
+		query = leaderboardMostWins
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, q.Theme, q.Gamemode, q.Since, q.Limit, q.Offset, time.Now().UTC().Truncate(0))
+	if err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+	defer rows.Close()
+
+	var entries []leaderboard.Entry
+	for rows.Next() {
+		var e leaderboard.Entry
+		if err := rows.Scan(&e.Rank, &e.Name, &e.Code, &e.DurationMs, &e.NumWins, &e.When); err != nil {
+			return nil, createError(handler.ErrorInternal, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, createError(handler.ErrorInternal, err)
+	}
+
+	return entries, nil
+}
+
+// submitted returns the ID of the attempt saved by a previous submission with the same
+// idempotency key, or uuid.Nil if there is none.
+func submitted(ctx context.Context, tx *sql.Tx, sub handler.Submission) (uuid.UUID, error) {
+	var id uuid.UUID
+	var hash []byte
+
+	err := tx.QueryRowContext(
+		ctx,
+		"select id, payload_hash from attempts where session_id = $1 and idempotency_key = $2",
+		sub.SessionID, sub.IdempotencyKey,
+	).Scan(&id, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	} else if err != nil {
+		return uuid.Nil, fmt.Errorf("get submitted attempt: %w", err)
+	}
+
+	if !bytes.Equal(hash, sub.PayloadHash) {
+		return uuid.Nil, createError(handler.ErrorKeyReused, fmt.Errorf("key %q belongs to attempt %s", sub.IdempotencyKey, id))
+	}
+
+	return id, nil
+}
+
+// challenge links the attempt to the shared record it was played against and counts it
+// towards the record's stats. Records which don't exist or are of another gamemode are ignored.
+func challenge(ctx context.Context, tx *sql.Tx, id uuid.UUID, sub handler.Submission) error {
+	const update = `update attempts set challenge = $1
+where id = $2 and gamemode = (select attempts.gamemode from links join attempts on attempts.id = links.attempt_id where links.code = $1)`
+
+	res, err := tx.ExecContext(ctx, update, sub.Challenge, id)
+	if err != nil {
+		return fmt.Errorf("set challenge: %w", err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("set challenge: %w", err)
+	} else if n == 0 {
+		return nil
+	}
+
+	// Like their visits, the attempts of creators playing against their own records aren't counted.
+	const count = `insert into link_visits (code, session_id, attempts, created_at, updated_at)
+select code, $2, 1, $3, $3 from links where code = $1 and coalesce(player_id, session_id) is not coalesce($4, $2)
+on conflict (code, coalesce(session_id, '')) do update set attempts = attempts + 1, updated_at = excluded.updated_at`
+
+	if _, err := tx.ExecContext(ctx, count, sub.Challenge, sub.SessionID, time.Now().UTC().Truncate(0), sub.PlayerID); err != nil {
+		return fmt.Errorf("count challenge: %w", err)
+	}
+
+	return nil
+}
+
+// consumeRand records that the attempt used the random stream from its offset up to end.
+// Attempts may use any part of the stream which no other attempt used: they aren't
+// necessarily submitted in the order they were played, for example when sent in a batch.
+//
+// Offsets wrap around, so windows are stored as their start offset and the offset
+// right after their end, which is past the wraparound for windows which cross it.
+// Two windows overlap if either one starts less than the other's length after the other's start,
+// counting modulo the offset space.
+func consumeRand(ctx context.Context, tx *sql.Tx, id uuid.UUID, rs attempt.RandState, end uint32) error {
+	n := end - rs.Offset
+	if int32(n) < 0 {
+		return createError(handler.ErrorRandUsed, fmt.Errorf("stream ends at %d before its start %d", end, rs.Offset))
+	} else if n == 0 {
+		return nil
+	}
+
+	const overlapping = `select start_offset, end_offset from rand_windows
+where mask = $1 and key_hi = $2 and key_lo = $3 and (
+	(($4 - start_offset) % $6 + $6) % $6 < end_offset - start_offset or
+	((start_offset - $4) % $6 + $6) % $6 < $5
+)
+limit 1`
+
+	var usedStart, usedEnd int64
+	err := tx.QueryRowContext(ctx, overlapping, rs.Mask, rs.Key[0], rs.Key[1], rs.Offset, n, int64(1)<<32).Scan(&usedStart, &usedEnd)
+	if err == nil {
+		return createError(handler.ErrorRandUsed, fmt.Errorf("stream used from %d to %d, overlaps [%d, %d)", rs.Offset, end, uint32(usedStart), uint32(usedEnd)))
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("get overlapping rand window: %w", err)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"insert into rand_windows (mask, key_hi, key_lo, start_offset, end_offset, attempt_id) values ($1, $2, $3, $4, $5, $6)",
+		rs.Mask, rs.Key[0], rs.Key[1], int64(rs.Offset), int64(rs.Offset)+int64(n), id,
+	)
+
+	var sqerr *sqlite.Error
+	if errors.As(err, &sqerr) && sqerr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return createError(handler.ErrorRandUsed, err)
+	} else if err != nil {
+		return fmt.Errorf("insert rand window: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]verify.Pending, error) {
+	const query = `update attempts set verification_claimed_at = $1
+where id in (
+	select id from attempts
+	where verification = 'PENDING' and (verification_claimed_at is null or verification_claimed_at < $2)
+	order by created_at
+	limit $3
+)
+returning id, gamemode, started_at, kind, num_squares, duration_ms, rand_state, trace`
+
+	now := time.Now().UTC().Truncate(0)
+
+	rows, err := r.DB.QueryContext(ctx, query, now, now.Add(-lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []verify.Pending
+	for rows.Next() {
+		var p verify.Pending
+		att := &p.Attempt
+
+		if err := rows.Scan(&p.ID, &att.Gamemode, &att.StartedAt, &att.Kind, &att.NumSquares, &att.DurationMs, (*randState)(&att.RandState), &p.Trace); err != nil {
+			return nil, fmt.Errorf("scan attempt: %w", err)
+		}
+
+		claimed = append(claimed, p)
+	}
+
+	return claimed, rows.Err()
+}
+
+func (r *Repository) SetVerification(ctx context.Context, id uuid.UUID, res verify.Result) error {
+	const query = `update attempts
+set verification = $1, verification_reason = $2, verification_heuristic = $3, verification_claimed_at = null, updated_at = $4
+where id = $5 and verification = 'PENDING'`
+
+	_, err := r.DB.ExecContext(ctx, query, res.Verification, nullString(res.Reason), res.Heuristic, time.Now().UTC().Truncate(0), id)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
+
 func (r *Repository) Ping(ctx context.Context) error { return r.DB.PingContext(ctx) }
 
 func createError(kind handler.ErrorKind, err error) handler.RepositoryError {
@@ -168,6 +816,43 @@ func attemptFromRecord(ctx context.Context, tx *sql.Tx, record share.Record, now
 	return id, nil
 }
 
+// attemptRecordData derives the data of a record from the attempt it references.
+// Data sent by the client must match it.
+func attemptRecordData(ctx context.Context, tx *sql.Tx, record share.Record) (share.RecordData, error) {
+	const query = `select gamemode, kind, duration_ms from attempts
+where verification in ('PENDING', 'VALID') and id = $1`
+
+	var gamemode attempt.Gamemode
+	var kind attempt.Kind
+	var durationMs float64
+	if err := tx.QueryRowContext(ctx, query, record.AttemptID.UUID).Scan(&gamemode, &kind, &durationMs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return share.RecordData{}, createError(handler.ErrorNotFound, err)
+		}
+
+		return share.RecordData{}, createError(handler.ErrorInternal, err)
+	}
+
+	if kind != attempt.Win {
+		return share.RecordData{}, createError(handler.ErrorNotWin, nil)
+	}
+
+	data, ok := share.AttemptData(gamemode, durationMs)
+	if !ok {
+		return share.RecordData{}, createError(handler.ErrorDataMismatch, fmt.Errorf("%s records can't be derived from a single attempt", gamemode))
+	}
+
+	if record.Data != (share.RecordData{}) && record.Data != data {
+		return share.RecordData{}, createError(handler.ErrorDataMismatch, fmt.Errorf("got %+v, attempt has %+v", record.Data, data))
+	}
+
+	return data, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 type randState attempt.RandState
 
 func (r randState) Value() (driver.Value, error) {
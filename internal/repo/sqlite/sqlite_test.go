@@ -0,0 +1,327 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang-migrate/migrate/v4"
+	msqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	resources "github.com/tmaxmax/popthegrid"
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/handler"
+	"github.com/tmaxmax/popthegrid/internal/handler/sessionrand"
+	"github.com/tmaxmax/popthegrid/internal/leaderboard"
+	"github.com/tmaxmax/popthegrid/internal/share"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+	"github.com/tmaxmax/popthegrid/internal/verify"
+)
+
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", t.TempDir()+"/db.sqlite?_pragma=foreign_keys(ON)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	md, err := msqlite.WithInstance(db, &msqlite.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := iofs.New(resources.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := migrate.NewWithInstance("embedFiles", fd, "sqlite", md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return &Repository{DB: db}
+}
+
+func newID() uuid.UUID { return uuid.Must(uuid.NewV4()) }
+
+func nullID(id uuid.UUID) uuid.NullUUID { return uuid.NullUUID{UUID: id, Valid: true} }
+
+func submitWin(t *testing.T, r *Repository, challenge share.Code, sessionID, playerID uuid.UUID, durationMs float64) {
+	t.Helper()
+
+	_, err := r.Submit(context.Background(), handler.Submission{
+		Attempt: &attempt.Attempt{
+			Gamemode:   attempt.GamemodePassthrough,
+			StartedAt:  time.Now().UTC().Truncate(0),
+			DurationMs: durationMs,
+			Kind:       attempt.Win,
+			NumSquares: 48,
+		},
+		Trace:     &trace.Trace{},
+		Result:    verify.Result{Verification: attempt.VerificationValid},
+		SessionID: sessionID,
+		PlayerID:  nullID(playerID),
+		Challenge: challenge,
+	})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+}
+
+func TestChallengersAcrossSessions(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+
+	creator, challenger := newID(), newID()
+
+	code, err := r.Save(ctx, share.Record{
+		Gamemode:  attempt.GamemodePassthrough,
+		Theme:     attempt.ThemeCandy,
+		Name:      "creator",
+		When:      time.Now().UTC().Truncate(0),
+		Data:      share.RecordData{FastestWinDuration: 5000},
+		SessionID: nullID(newID()),
+		PlayerID:  nullID(creator),
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// The challenger plays from two sessions and shares a record from the second one.
+	first, second := newID(), newID()
+	submitWin(t, r, code, first, challenger, 4000)
+	submitWin(t, r, code, second, challenger, 3000)
+
+	if _, err := r.Save(ctx, share.Record{
+		Gamemode:  attempt.GamemodePassthrough,
+		Theme:     attempt.ThemeCandy,
+		Name:      "challenger",
+		When:      time.Now().UTC().Truncate(0),
+		Data:      share.RecordData{FastestWinDuration: 3000},
+		SessionID: nullID(second),
+		PlayerID:  nullID(challenger),
+	}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// The creator plays against their own record after their session rotated.
+	submitWin(t, r, code, newID(), creator, 1000)
+
+	challengers, err := r.Challengers(ctx, code, attempt.GamemodePassthrough)
+	if err != nil {
+		t.Fatalf("challengers: %v", err)
+	}
+
+	if len(challengers) != 1 {
+		t.Fatalf("got %d challengers, expected 1: %+v", len(challengers), challengers)
+	}
+
+	if c := challengers[0]; c.Name != "challenger" || c.Data.FastestWinDuration != 3000 {
+		t.Errorf("got challenger %q with %vms, expected challenger with 3000ms", c.Name, c.Data.FastestWinDuration)
+	}
+}
+
+func TestGetInvalidatedRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		result verify.Result
+		gone   bool
+	}{
+		{
+			name:   "contradicted by its trace",
+			result: verify.Result{Verification: attempt.VerificationInvalid, Reason: "tampered"},
+			gone:   true,
+		},
+		{
+			name:   "rejected by a heuristic",
+			result: verify.Result{Verification: attempt.VerificationInvalid, Reason: "too fast", Heuristic: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := newTestRepository(t)
+
+			sessionID := newID()
+			id, err := r.Submit(ctx, handler.Submission{
+				Attempt: &attempt.Attempt{
+					Gamemode:   attempt.GamemodePassthrough,
+					StartedAt:  time.Now().UTC().Truncate(0),
+					DurationMs: 2000,
+					Kind:       attempt.Win,
+					NumSquares: 48,
+				},
+				Trace:     &trace.Trace{},
+				Result:    verify.Result{Verification: attempt.VerificationPending},
+				SessionID: sessionID,
+			})
+			if err != nil {
+				t.Fatalf("submit: %v", err)
+			}
+
+			code, err := r.Save(ctx, share.Record{
+				Gamemode:  attempt.GamemodePassthrough,
+				Theme:     attempt.ThemeCandy,
+				When:      time.Now().UTC().Truncate(0),
+				AttemptID: uuid.NullUUID{UUID: id, Valid: true},
+				SessionID: nullID(sessionID),
+			})
+			if err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			if _, err := r.Get(ctx, code); err != nil {
+				t.Fatalf("get pending record: %v", err)
+			}
+
+			if err := r.SetVerification(ctx, id, tt.result); err != nil {
+				t.Fatalf("set verification: %v", err)
+			}
+
+			_, err = r.Get(ctx, code)
+			if tt.gone {
+				if rerr := (handler.RepositoryError{}); !errors.As(err, &rerr) || rerr.Kind != handler.ErrorGone {
+					t.Errorf("got error %v, expected the record to be gone", err)
+				}
+			} else if err != nil {
+				t.Errorf("get record: %v", err)
+			}
+		})
+	}
+}
+
+func TestLeaderboardRanksAnonymousPlayers(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+
+	named, anonymous := newID(), newID()
+
+	code, err := r.Save(ctx, share.Record{
+		Gamemode:  attempt.GamemodePassthrough,
+		Theme:     attempt.ThemeCandy,
+		Name:      "named",
+		When:      time.Now().UTC().Truncate(0),
+		Data:      share.RecordData{FastestWinDuration: 5000},
+		SessionID: nullID(newID()),
+		PlayerID:  nullID(named),
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	for _, win := range []struct {
+		player     uuid.UUID
+		durationMs float64
+	}{{named, 3000}, {anonymous, 2000}} {
+		_, err := r.Submit(ctx, handler.Submission{
+			Attempt: &attempt.Attempt{
+				Gamemode:   attempt.GamemodePassthrough,
+				StartedAt:  time.Now().UTC().Truncate(0),
+				DurationMs: win.durationMs,
+				Kind:       attempt.Win,
+				NumSquares: 48,
+			},
+			Trace:     &trace.Trace{Events: trace.Events{trace.EventTheme{Name: attempt.ThemeCandy}}},
+			Result:    verify.Result{Verification: attempt.VerificationValid},
+			SessionID: newID(),
+			PlayerID:  nullID(win.player),
+		})
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+
+	entries, err := r.Leaderboard(ctx, leaderboard.Query{Gamemode: attempt.GamemodePassthrough, Theme: attempt.ThemeCandy, Limit: 10})
+	if err != nil {
+		t.Fatalf("leaderboard: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, expected 2: %+v", len(entries), entries)
+	}
+
+	if e := entries[0]; e.Rank != 1 || e.Name != "" || e.Code != "" || e.DurationMs != 2000 {
+		t.Errorf("got first entry %+v, expected the anonymous player's win of 2000ms", e)
+	}
+	if e := entries[1]; e.Rank != 2 || e.Name != "named" || e.Code != code || e.DurationMs != 3000 {
+		t.Errorf("got second entry %+v, expected the named player's win of 3000ms under %s", e, code)
+	}
+}
+
+func TestConsumeRand(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepository(t)
+
+	// windows are the [start, end) offsets of the attempts using a stream, submitted in order.
+	tests := []struct {
+		name    string
+		windows [][2]uint32
+		// used is the index of the first window which must be rejected, or -1.
+		used int
+	}{
+		{name: "consecutive", windows: [][2]uint32{{10, 20}, {20, 30}}, used: -1},
+		{name: "gap", windows: [][2]uint32{{10, 20}, {40, 50}}, used: -1},
+		{name: "exact reuse", windows: [][2]uint32{{10, 20}, {10, 20}}, used: 1},
+		{name: "overlapping the end", windows: [][2]uint32{{10, 20}, {15, 25}}, used: 1},
+		{name: "overlapping the start", windows: [][2]uint32{{10, 20}, {5, 15}}, used: 1},
+		{name: "containing", windows: [][2]uint32{{10, 20}, {5, 25}}, used: 1},
+		// Attempts aren't submitted in the order they're played.
+		{name: "disjoint before", windows: [][2]uint32{{10, 20}, {0, 5}}, used: -1},
+		{name: "between two windows", windows: [][2]uint32{{0, 10}, {30, 40}, {10, 30}}, used: -1},
+		{name: "overlapping an earlier window", windows: [][2]uint32{{20, 30}, {40, 50}, {0, 25}}, used: 2},
+		{name: "ending before its start", windows: [][2]uint32{{20, 10}}, used: 0},
+		{name: "wrapping around", windows: [][2]uint32{{math.MaxUint32 - 5, 5}, {5, 15}, {20, 30}}, used: -1},
+		{name: "reuse across the wraparound", windows: [][2]uint32{{math.MaxUint32 - 5, 5}, {math.MaxUint32, 10}}, used: 1},
+		{name: "reuse after the wraparound", windows: [][2]uint32{{math.MaxUint32 - 5, 5}, {5, 15}, {0, 10}}, used: 2},
+		{name: "reuse of a window before the wraparound", windows: [][2]uint32{{math.MaxUint32 - 20, math.MaxUint32 - 10}, {5, 15}, {math.MaxUint32 - 20, math.MaxUint32 - 10}}, used: 2},
+		{name: "before the wraparound after it", windows: [][2]uint32{{5, 15}, {math.MaxUint32 - 20, math.MaxUint32 - 10}, {math.MaxUint32 - 5, 5}}, used: -1},
+		{name: "across the wraparound overlapping a window after it", windows: [][2]uint32{{5, 15}, {math.MaxUint32 - 5, 10}}, used: 1},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := attempt.RandState{Rand: sessionrand.Rand{Mask: uint32(i), Key: [2]uint32{1, 2}}}
+
+			for j, w := range tt.windows {
+				rs.Offset = w[0]
+				_, err := r.Submit(ctx, handler.Submission{
+					Attempt: &attempt.Attempt{
+						Gamemode:   attempt.GamemodeSameSquare,
+						StartedAt:  time.Now().UTC().Truncate(0),
+						DurationMs: 2000,
+						Kind:       attempt.Win,
+						NumSquares: 48,
+						RandState:  rs,
+					},
+					Trace:     &trace.Trace{},
+					Result:    verify.Result{Verification: attempt.VerificationValid, RandEnd: w[1]},
+					SessionID: newID(),
+				})
+
+				rerr := handler.RepositoryError{}
+				used := errors.As(err, &rerr) && rerr.Kind == handler.ErrorRandUsed
+				if err != nil && !used {
+					t.Fatalf("window %d: submit: %v", j, err)
+				}
+
+				if expected := j == tt.used; used != expected {
+					t.Fatalf("window %d [%d, %d): got used %t, expected %t", j, w[0], w[1], used, expected)
+				} else if used {
+					return
+				}
+			}
+		})
+	}
+}
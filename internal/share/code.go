@@ -5,37 +5,83 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"strings"
+
+	"github.com/tmaxmax/popthegrid/internal/displayname"
 )
 
 type Code string
 
+// Validate checks whether c is a well-formed code, either random or vanity.
 func (c Code) Validate() error {
-	if len(c) != codeLength {
-		return fmt.Errorf("code length is %q, must be %q", len(c), codeLength)
+	if len(c) < minVanityLength || len(c) > maxVanityLength {
+		return fmt.Errorf("code length is %d, must be between %d and %d", len(c), minVanityLength, maxVanityLength)
 	}
 
 	for i := range c {
-		if strings.IndexByte(chars, c[i]) == -1 {
+		if strings.IndexByte(vanityChars, c[i]) == -1 {
 			return errors.New("code contains invalid characters")
 		}
 	}
 
+	if isSeparator(c[0]) || isSeparator(c[len(c)-1]) {
+		return errors.New("code must start and end with a letter or digit")
+	}
+
 	return nil
 }
 
+// ValidateVanity checks whether c can be requested as a vanity code.
+// Codes which are reserved for other uses or which contain a blocklisted word are rejected.
+// Words are only matched on their own, as rejected codes are never reviewed.
+func (c Code) ValidateVanity(blocklist displayname.Blocklist) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	if _, ok := reservedCodes[strings.ToLower(string(c))]; ok {
+		return fmt.Errorf("code %q is reserved", c)
+	} else if blocklist.MatchesWords(string(c)) {
+		return fmt.Errorf("code %q is not allowed", c)
+	}
+
+	return nil
+}
+
+func isSeparator(b byte) bool {
+	return b == '-' || b == '_'
+}
+
+// reservedCodes are the words which might be used by routes, or which could mislead players.
+// Vanity codes are compared with them regardless of case.
+var reservedCodes = map[string]struct{}{
+	"about": {}, "admin": {}, "api": {}, "assets": {}, "attempts": {}, "auth": {},
+	"health": {}, "help": {}, "index": {}, "leaderboard": {}, "login": {}, "logout": {},
+	"manifest": {}, "moderator": {}, "official": {}, "og": {}, "popthegrid": {},
+	"session": {}, "settings": {}, "share": {}, "src": {}, "static": {}, "submit": {},
+	"support": {},
+}
+
 const (
-	codeLength    = 6
+	// Random codes start at minCodeLength characters and are lengthened as their space fills.
+	minCodeLength = 6
+	maxCodeLength = 12
+	// Vanity codes are chosen by players and can also contain separators.
+	minVanityLength = 4
+	maxVanityLength = 24
+	vanityChars     = chars + "-_"
+
 	chars         = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	letterIdxBits = 6
 	letterIdxMask = 1<<letterIdxBits - 1
 	letterIdxMax  = 63 / letterIdxBits
 )
 
-func NewCode() Code {
+// NewCode returns a random code with the given number of characters.
+func NewCode(length int) Code {
 	var sb strings.Builder
-	sb.Grow(codeLength)
+	sb.Grow(length)
 
-	for i, cache, remain := codeLength-1, rand.Int64(), letterIdxMax; i >= 0; {
+	for i, cache, remain := length-1, rand.Int64(), letterIdxMax; i >= 0; {
 		if remain == 0 {
 			cache, remain = rand.Int64(), letterIdxMax
 		}
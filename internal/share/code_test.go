@@ -0,0 +1,44 @@
+package share
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmaxmax/popthegrid/internal/displayname"
+)
+
+func TestValidateVanity(t *testing.T) {
+	blocklist, err := displayname.ParseBlocklist(strings.NewReader("ass\ncunt\nfuck\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code  Code
+		valid bool
+	}{
+		{"tournament-2026", true},
+		// Blocked words inside other words are fine.
+		{"cass-dev", true},
+		{"Scunthorpe", true},
+		{"grass_hopper", true},
+		{"classAct", true},
+		{"passthrough", true},
+		{"big-ass", false},
+		{"BigAss", false},
+		{"a55_hat", false},
+		// Separators don't hide a blocked word.
+		{"f-u-c-k", false},
+		{"Admin", false},
+		{"-popgrid", false},
+	}
+
+	for _, tt := range tests {
+		err := tt.code.ValidateVanity(blocklist)
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.code, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%s: expected invalid", tt.code)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package share
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// maxLoad is the largest share of the codes of a length which are taken
+	// before CodeGenerator.Fit picks a longer length.
+	maxLoad = 0.01
+	// maxCollisionRate is the average share of generated codes which are taken,
+	// above which codes are lengthened.
+	maxCollisionRate = 0.05
+	// collisionWeight is the weight of each generated code in the average collision rate.
+	collisionWeight = 0.02
+)
+
+// CodeGenerator generates random codes, lengthening them when too many collide with existing ones.
+// The zero value generates the shortest codes. It is safe for concurrent use.
+type CodeGenerator struct {
+	mu     sync.Mutex
+	length int
+	// rate is the moving average of the collisions of generated codes.
+	rate float64
+}
+
+// Fit sets the length of the codes to the shortest one at which the given number of taken codes
+// fill little of the space. Codes are never shortened.
+func (g *CodeGenerator) Fit(taken int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	length := max(g.length, minCodeLength)
+	for length < maxCodeLength && float64(taken) > maxLoad*math.Pow(float64(len(chars)), float64(length)) {
+		length++
+	}
+
+	g.length = length
+}
+
+// New generates a code of the current length.
+func (g *CodeGenerator) New() Code {
+	return NewCode(g.Length())
+}
+
+func (g *CodeGenerator) Length() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return max(g.length, minCodeLength)
+}
+
+// Observe records whether a generated code was taken. It reports whether
+// the collision rate became too high and the codes were lengthened.
+func (g *CodeGenerator) Observe(collided bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var x float64
+	if collided {
+		x = 1
+	}
+
+	g.rate += collisionWeight * (x - g.rate)
+	if g.rate <= maxCollisionRate {
+		return false
+	}
+
+	return g.grow()
+}
+
+// Grow lengthens the codes. It reports false if they are at the maximum length already.
+func (g *CodeGenerator) Grow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.grow()
+}
+
+func (g *CodeGenerator) grow() bool {
+	length := max(g.length, minCodeLength)
+	if length == maxCodeLength {
+		return false
+	}
+
+	g.length = length + 1
+	// Longer codes are less crowded, so the rate is measured anew.
+	g.rate = 0
+
+	return true
+}
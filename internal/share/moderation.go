@@ -0,0 +1,11 @@
+package share
+
+import "time"
+
+// HeldName is a name held back from its record until a moderator decides on it.
+type HeldName struct {
+	ID        int64     `json:"id"`
+	Code      Code      `json:"code"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,65 @@
+package share
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/crypto/macval"
+)
+
+// OwnerToken proves that its bearer created the record shared under Code.
+type OwnerToken struct {
+	Code Code
+}
+
+// ownerTokenPrefix keeps other values signed with the same key from passing as owner tokens.
+var ownerTokenPrefix = []byte("share-owner:")
+
+func (t OwnerToken) MarshalBinary() ([]byte, error) {
+	return append(bytes.Clone(ownerTokenPrefix), t.Code...), nil
+}
+
+func (t *OwnerToken) UnmarshalBinary(data []byte) error {
+	code, ok := bytes.CutPrefix(data, ownerTokenPrefix)
+	if !ok {
+		return errors.New("not an owner token")
+	}
+
+	t.Code = Code(code)
+
+	return t.Code.Validate()
+}
+
+func (t OwnerToken) Sign(key []byte) string {
+	val, _ := macval.To(t, macval.Options{Algorithm: sha256.New, Key: key})
+	return val
+}
+
+func ParseOwnerToken(s string, key []byte) (OwnerToken, error) {
+	var t OwnerToken
+	if err := macval.From(s, macval.Options{Algorithm: sha256.New, Key: key}, &t); err != nil {
+		return OwnerToken{}, err
+	}
+
+	return t, nil
+}
+
+// Edit changes a shared record. Fields which are not set are left unchanged.
+type Edit struct {
+	Name      *string    `json:"name"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (e *Edit) Validate(now time.Time) error {
+	if e.Name == nil && e.ExpiresAt == nil {
+		return errors.New("nothing to change")
+	}
+
+	if e.ExpiresAt != nil && !e.ExpiresAt.After(now) {
+		return errors.New("expiry must be in the future")
+	}
+
+	return nil
+}
@@ -3,11 +3,11 @@ package share
 import (
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/locale"
 )
 
 type RecordData struct {
@@ -24,10 +24,35 @@ type Record struct {
 	When      time.Time        `json:"when"`
 	Data      RecordData       `json:"data"`
 	AttemptID uuid.NullUUID    `json:"attemptID,omitzero"`
+	// SessionID is the session which shared the record.
+	SessionID uuid.NullUUID `json:"-"`
+	// PlayerID is the player of that session.
+	PlayerID uuid.NullUUID `json:"-"`
+}
+
+// AttemptData returns the record data of a single winning attempt. Records of gamemodes
+// which count wins can't be derived from a single attempt.
+func AttemptData(gamemode attempt.Gamemode, durationMs float64) (RecordData, bool) {
+	switch gamemode {
+	case attempt.GamemodeOddOneOut, attempt.GamemodeSameSquare, attempt.GamemodePassthrough:
+		return RecordData{FastestWinDuration: durationMs}, true
+	default:
+		return RecordData{}, false
+	}
+}
+
+// BeatenBy reports whether the given data is a better result than the record's.
+func (r *Record) BeatenBy(d RecordData) bool {
+	if r.Gamemode == attempt.GamemodeRandom {
+		return d.NumWins > r.Data.NumWins
+	}
+
+	return d.FastestWinDuration < r.Data.FastestWinDuration
 }
 
 func (r *Record) Validate() error {
-	if r.Data == (RecordData{}) {
+	// Data of records which reference attempts is derived from the attempt.
+	if r.Data == (RecordData{}) && !r.AttemptID.Valid {
 		return errors.New("data must be provided")
 	}
 
@@ -46,50 +71,21 @@ func (r *Record) Validate() error {
 	return nil
 }
 
-func (r *Record) Description() string {
-	name := r.Name
-	if name == "" {
-		name = "your friend"
-	}
-
-	root := fmt.Sprintf("You're in %s world:", makePossessive(name))
-
-	switch r.Gamemode {
-	case attempt.GamemodeRandom:
-		numWins := r.Data.NumWins
-		return fmt.Sprintf("%s can you pop all squares more times? They did it %d %s.", root, numWins, makePlural("time", numWins))
-	case attempt.GamemodeSameSquare:
-		return fmt.Sprintf("%s zerstöre schneller die gleichen Karos! They did it in %s.", root, formatDuration(r.Data.FastestWinDuration))
-	case attempt.GamemodePassthrough:
-		return fmt.Sprintf("%s are your fingers the fastest? Pop all squares in %s to win!", root, formatDuration(r.Data.FastestWinDuration))
-	case attempt.GamemodeOddOneOut:
-		return fmt.Sprintf("%s can you pop the odd square quicker? Finish in under %s to win!", root, formatDuration(r.Data.FastestWinDuration))
-	default:
-		panic(fmt.Errorf("unknown gamemode %q", r.Gamemode))
-	}
-}
-
-func formatDuration(ms float64) string {
-	dur := time.Duration(ms) * time.Millisecond
-	if dur > time.Second {
-		dur = dur.Round(time.Second / 100)
-	}
-
-	return dur.String()
-}
-
-func makePossessive(s string) string {
-	if strings.HasSuffix(s, "s") || strings.HasSuffix(s, "z") {
-		return s + "'"
+// Description describes the record in the given locale, to whoever opens its link.
+func (r *Record) Description(l *locale.Locale) string {
+	score := l.Duration(r.Data.FastestWinDuration)
+	if r.Gamemode == attempt.GamemodeRandom {
+		score = l.Times(r.Data.NumWins)
 	}
 
-	return s + "'s"
+	return l.Record(r.Gamemode, r.Name, score)
 }
 
-func makePlural(s string, count int) string {
-	if count > 1 {
-		return s + "s"
+// Score formats the record's data in the given locale.
+func (r *Record) Score(l *locale.Locale) string {
+	if r.Gamemode == attempt.GamemodeRandom {
+		return l.Wins(r.Data.NumWins)
 	}
 
-	return s
+	return l.Duration(r.Data.FastestWinDuration)
 }
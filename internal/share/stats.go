@@ -0,0 +1,22 @@
+package share
+
+import "time"
+
+// Stats tell how a shared record was received.
+type Stats struct {
+	Opens int `json:"opens"`
+	// UniqueSessions is the number of sessions which opened the link or played against it.
+	UniqueSessions int `json:"uniqueSessions"`
+	// ChallengeAttempts is the number of attempts submitted while playing against the record.
+	ChallengeAttempts int `json:"challengeAttempts"`
+}
+
+// Challenger is the best verified result of a player against a shared record.
+type Challenger struct {
+	Name string     `json:"name,omitempty"`
+	Data RecordData `json:"data"`
+	// When is when the best win was started, or the last win for the random gamemode.
+	When time.Time `json:"when"`
+	// Beats tells whether the result is better than the record.
+	Beats bool `json:"beats"`
+}
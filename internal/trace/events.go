@@ -97,6 +97,59 @@ func (e *Events) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+func (e Events) MarshalJSON() ([]byte, error) {
+	buf := []byte{'['}
+
+	for i, ev := range e {
+		var typ string
+		switch ev.(type) {
+		case GameEventPrepare:
+			typ = "prepare"
+		case GameEventForceEnd:
+			typ = "forceEnd"
+		case GameEventPause:
+			typ = "pause"
+		case GameEventResume:
+			typ = "resume"
+		case GameEventRemoveSquare:
+			typ = "removeSquare"
+		case EventViewport:
+			typ = "viewport"
+		case EventOrientationChange:
+			typ = "orientationChange"
+		case EventGridResize:
+			typ = "gridResize"
+		case EventTheme:
+			typ = "theme"
+		default:
+			return nil, fmt.Errorf("unknown event type %T", ev)
+		}
+
+		t, err := Timestamp(ev.Time()).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return nil, fmt.Errorf("encode element: %w", err)
+		}
+
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		// The type and time are added to the object of the event's fields.
+		buf = fmt.Appendf(buf, `{"type":%q,"time":%s`, typ, t)
+		if len(data) > 2 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, data[1:]...)
+	}
+
+	return append(buf, ']'), nil
+}
+
 type Viewport struct {
 	Size   XY[float64] `json:"size"`
 	Offset XY[float64] `json:"off"`
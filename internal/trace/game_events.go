@@ -3,6 +3,8 @@ package trace
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,25 @@ type Square struct {
 	Col   int    `json:"col"`
 }
 
+// NumColors is the number of square colors of a theme.
+const NumColors = 5
+
+// ColorIndex returns the index of the square's color in its theme's palette.
+// The client records colors as references to the theme's CSS variables.
+func (s Square) ColorIndex() (int, bool) {
+	v, ok := strings.CutPrefix(s.Color, "var(--color-square-")
+	if ok {
+		v, ok = strings.CutSuffix(v, ")")
+	}
+
+	n, err := strconv.Atoi(v)
+	if !ok || err != nil || n < 1 || n > NumColors {
+		return 0, false
+	}
+
+	return n - 1, true
+}
+
 type GameEventRemoveSquare struct {
 	pointerEvent      *PointerEvent
 	Square            Square        `json:"square"`
@@ -2,6 +2,8 @@ package trace
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
 	"time"
 )
 
@@ -35,6 +37,44 @@ func (p *PointerEvent) parse(b []byte, prevT time.Duration) {
 	p.T = prevT + time.Duration(delta*5)*time.Microsecond
 }
 
+// inverse5 is the multiplicative inverse of 5 modulo 2^32. Deltas are multiplied
+// by 5 as 32-bit integers when parsed, so they're recovered by multiplying with it.
+const inverse5 = 0xCCCCCCCD
+
+func (p PointerEvent) encode(b []byte, prevT time.Duration) ([]byte, error) {
+	delta := p.T - prevT
+	if p.PointerIndex < 0 || p.PointerIndex > math.MaxUint8 {
+		return nil, fmt.Errorf("pointer index %d can't be encoded", p.PointerIndex)
+	} else if delta < 0 || delta%time.Microsecond != 0 || delta/time.Microsecond > math.MaxUint32 {
+		return nil, fmt.Errorf("time delta %v can't be encoded", delta)
+	}
+
+	enc := binary.LittleEndian
+
+	b = append(b, byte(p.PointerIndex))
+	b = enc.AppendUint16(b, p.Position.X)
+	b = enc.AppendUint16(b, p.Position.Y)
+
+	return enc.AppendUint32(b, uint32(delta/time.Microsecond)*inverse5), nil
+}
+
+// PointerEventsPayload encodes the pointer events in the format read by SetPointerEvents.
+func (t *Trace) PointerEventsPayload() ([]byte, error) {
+	b := make([]byte, 0, 9*len(t.PointerEvents))
+
+	prevT := time.Duration(t.FirstPointerEvent)
+	for i, p := range t.PointerEvents {
+		var err error
+		if b, err = p.encode(b, prevT); err != nil {
+			return nil, fmt.Errorf("pointer event %d: %w", i, err)
+		}
+
+		prevT = p.T
+	}
+
+	return b, nil
+}
+
 func parsePointerEvents(payload []byte, firstT time.Duration) []PointerEvent {
 	events := make([]PointerEvent, len(payload)/9)
 	for i := range events {
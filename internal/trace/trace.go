@@ -9,7 +9,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
 )
 
 type Trace struct {
@@ -31,6 +34,26 @@ func (t *Trace) SetPointerEvents(payload []byte) error {
 	return t.setPointers()
 }
 
+// Theme returns the theme the game was played in: the last one chosen before the first
+// removal, or the first one if it was chosen later. It returns the empty theme if none was chosen.
+func (t *Trace) Theme() attempt.Theme {
+	var theme attempt.Theme
+	var removed bool
+
+	for _, e := range t.Events {
+		switch e := e.(type) {
+		case EventTheme:
+			if theme == "" || !removed {
+				theme = e.Name
+			}
+		case GameEventRemoveSquare:
+			removed = true
+		}
+	}
+
+	return theme
+}
+
 func (t *Trace) Compress(w io.Writer) error {
 	gw := gzip.NewWriter(w)
 	defer gw.Close()
@@ -118,6 +141,21 @@ func (t *Timestamp) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MarshalJSON encodes the timestamp as the milliseconds which UnmarshalJSON
+// truncates back to it: dividing alone could land just below.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	v := float64(t) / float64(time.Millisecond)
+	for range 8 {
+		if Timestamp(v*float64(time.Millisecond)) == t {
+			break
+		}
+
+		v = math.Nextafter(v, math.Copysign(math.Inf(1), v))
+	}
+
+	return json.Marshal(v)
+}
+
 type XY[T any] struct {
 	X, Y T
 }
@@ -134,6 +172,10 @@ func (x *XY[T]) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+func (x XY[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]T{x.X, x.Y})
+}
+
 func init() {
 	gob.Register(EventTheme{})
 	gob.Register(EventViewport{})
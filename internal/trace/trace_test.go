@@ -0,0 +1,88 @@
+package trace
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// traceJSON is a trace in the submission format. Its times have fractional
+// milliseconds which aren't exactly representable as floats.
+const traceJSON = `{
+	"metadata": {"maxTouchPoints": 5, "primaryPointer": 2, "anyPointer": 1},
+	"events": [
+		{"type": "viewport", "time": 0.1, "data": {"size": [390, 844], "off": [0, 0], "scale": 1}},
+		{"type": "orientationChange", "time": 0.3, "data": {"type": "portrait-primary", "angle": 0}},
+		{"type": "theme", "time": 1.7, "name": "noir"},
+		{"type": "gridResize", "time": 2.0045075, "data": {"anchor": [15, 120.5], "sideLength": 60, "cols": 6, "numSquares": 48}, "windowSize": [390, 844]},
+		{"type": "prepare", "time": 10.123, "animation": "short"},
+		{"type": "removeSquare", "time": 1234.567, "square": {"color": "var(--color-square-2)", "row": 0, "col": 3}, "pointerEventIndex": 1},
+		{"type": "pause", "time": 2000.001, "pause": "visibility"},
+		{"type": "resume", "time": 2500.999, "pause": "visibility"},
+		{"type": "removeSquare", "time": 3000.3, "square": {"color": "var(--color-square-5)", "row": 2, "col": 1}, "pointerEventIndex": 2},
+		{"type": "forceEnd", "time": 3100.7, "canRestart": true}
+	],
+	"pointers": [{"type": "touch", "size": [24.5, 24.5], "primary": true, "move": false}],
+	"timeOrigin": 1760688000123.456,
+	"firstPointerEventTime": 1200.789
+}`
+
+// pointerEventsPayload encodes pointer events of the first pointer, with deltas
+// in units of 5 microseconds.
+func pointerEventsPayload(events ...[3]uint32) []byte {
+	var b []byte
+	for _, e := range events {
+		b = append(b, 0)
+		b = binary.LittleEndian.AppendUint16(b, uint16(e[0]))
+		b = binary.LittleEndian.AppendUint16(b, uint16(e[1]))
+		b = binary.LittleEndian.AppendUint32(b, e[2])
+	}
+
+	return b
+}
+
+func parseTrace(t *testing.T, data, payload []byte) *Trace {
+	t.Helper()
+
+	var tr Trace
+	if err := json.Unmarshal(data, &tr); err != nil {
+		t.Fatalf("parse trace: %v", err)
+	}
+	if err := tr.SetPointerEvents(payload); err != nil {
+		t.Fatalf("set pointer events: %v", err)
+	}
+
+	return &tr
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	payload := pointerEventsPayload([3]uint32{100, 200, 0}, [3]uint32{180, 210, 6753}, [3]uint32{120, 330, 353111})
+	tr := parseTrace(t, []byte(traceJSON), payload)
+
+	data, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatalf("encode trace: %v", err)
+	}
+
+	encodedPayload, err := tr.PointerEventsPayload()
+	if err != nil {
+		t.Fatalf("encode pointer events: %v", err)
+	}
+
+	reparsed := parseTrace(t, data, encodedPayload)
+	if !reflect.DeepEqual(tr, reparsed) {
+		t.Errorf("re-parsed trace differs:\n%+v\n%+v", tr, reparsed)
+	}
+}
+
+func TestTimestampTruncates(t *testing.T) {
+	var ts Timestamp
+	if err := json.Unmarshal([]byte("1.0000009"), &ts); err != nil {
+		t.Fatal(err)
+	}
+
+	if ts != 1_000_000 {
+		t.Errorf("parsed %d nanoseconds, expected 1000000", ts)
+	}
+}
@@ -0,0 +1,118 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// durationTolerance is the maximum allowed difference between the claimed
+// attempt duration and the one measured from the trace.
+const durationTolerance = 50 * time.Millisecond
+
+// Activity describes when an attempt was played.
+type Activity struct {
+	// Start is the time of the first removal. The client starts the attempt's
+	// clock with it, not with the prepare event, which precedes the grid's creation.
+	Start time.Duration
+	// End is the time of the final removal.
+	End time.Duration
+	// Paused is the time the game spent paused between Start and End.
+	Paused time.Duration
+}
+
+// Active returns the time spent playing.
+func (a Activity) Active() time.Duration { return a.End - a.Start - a.Paused }
+
+// Check tells whether the claimed duration of the attempt matches the activity.
+// A duration which includes the pauses is also accepted.
+func (a Activity) Check(att *attempt.Attempt) error {
+	claimed := time.Duration(att.DurationMs * float64(time.Millisecond))
+	if claimed < a.Active()-durationTolerance || claimed > a.End-a.Start+durationTolerance {
+		return fmt.Errorf("attempt lasted %v, trace shows %v of play and %v of pauses", claimed, a.Active(), a.Paused)
+	}
+
+	return nil
+}
+
+// ActivityOf reconstructs the activity of the attempt recorded by the trace.
+// Pauses and resumes are paired by their token: while the game is paused, other pauses
+// may happen, but they must be resumed before the one which paused the game.
+// Unbalanced pauses, overlapping pauses and removals during a pause are rejected.
+func ActivityOf(tr *trace.Trace) (Activity, error) {
+	var a Activity
+	var started bool
+
+	var pausedBy string
+	var pausedAt time.Duration
+	// pauses are the intervals the game was paused for, which are known to
+	// overlap the activity only once the final removal is found.
+	var pauses [][2]time.Duration
+	nested := map[string]bool{}
+	seen := map[string]bool{}
+
+	for _, e := range tr.Events {
+		switch e := e.(type) {
+		case trace.GameEventPause:
+			if seen[e.Token] {
+				return Activity{}, fmt.Errorf("pause %q happened twice", e.Token)
+			}
+			seen[e.Token] = true
+
+			if pausedBy == "" {
+				pausedBy, pausedAt = e.Token, e.T
+			} else {
+				nested[e.Token] = true
+			}
+		case trace.GameEventResume:
+			switch {
+			case nested[e.Token]:
+				delete(nested, e.Token)
+			case e.Token == pausedBy && pausedBy != "":
+				if len(nested) > 0 {
+					return Activity{}, fmt.Errorf("pause %q resumed before the pauses inside it", e.Token)
+				}
+
+				pauses = append(pauses, [2]time.Duration{pausedAt, e.T})
+				pausedBy = ""
+			default:
+				return Activity{}, fmt.Errorf("resume %q has no matching pause", e.Token)
+			}
+		case trace.GameEventRemoveSquare:
+			if pausedBy != "" {
+				return Activity{}, fmt.Errorf("square removed during pause %q", pausedBy)
+			}
+
+			if !started {
+				a.Start, started = e.T, true
+			}
+			a.End = e.T
+		}
+	}
+
+	if pausedBy != "" {
+		return Activity{}, fmt.Errorf("pause %q was never resumed", pausedBy)
+	} else if !started {
+		return Activity{}, errors.New("no squares removed")
+	}
+
+	for _, p := range pauses {
+		if from, to := max(p[0], a.Start), min(p[1], a.End); to > from {
+			a.Paused += to - from
+		}
+	}
+
+	return a, nil
+}
+
+func checkDuration(att *attempt.Attempt, tr *trace.Trace) error {
+	a, err := ActivityOf(tr)
+	if err != nil {
+		return err
+	}
+
+	return a.Check(att)
+}
@@ -0,0 +1,65 @@
+package verify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+func TestActivityOfClipsPauses(t *testing.T) {
+	ms := func(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+	tests := []struct {
+		name   string
+		events trace.Events
+		paused time.Duration
+	}{
+		{
+			name: "pause between removals",
+			events: trace.Events{
+				trace.GameEventRemoveSquare{T: ms(100)},
+				trace.GameEventPause{Token: "a", T: ms(200)},
+				trace.GameEventResume{Token: "a", T: ms(500)},
+				trace.GameEventRemoveSquare{T: ms(600)},
+			},
+			paused: ms(300),
+		},
+		{
+			name: "pause before the first removal",
+			events: trace.Events{
+				trace.GameEventPause{Token: "a", T: ms(0)},
+				trace.GameEventResume{Token: "a", T: ms(50)},
+				trace.GameEventRemoveSquare{T: ms(100)},
+				trace.GameEventRemoveSquare{T: ms(600)},
+			},
+			paused: 0,
+		},
+		{
+			name: "pause after the final removal",
+			events: trace.Events{
+				trace.GameEventRemoveSquare{T: ms(100)},
+				trace.GameEventRemoveSquare{T: ms(600)},
+				trace.GameEventPause{Token: "a", T: ms(700)},
+				trace.GameEventResume{Token: "a", T: ms(10000)},
+			},
+			paused: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ActivityOf(&trace.Trace{Events: tt.events})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if a.Paused != tt.paused {
+				t.Errorf("paused for %v, expected %v", a.Paused, tt.paused)
+			}
+			if a.Active() != ms(500)-tt.paused {
+				t.Errorf("active for %v, expected %v", a.Active(), ms(500)-tt.paused)
+			}
+		})
+	}
+}
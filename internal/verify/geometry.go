@@ -0,0 +1,43 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/grid"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// geometry checks that every recorded grid layout is the one the client would compute.
+// The grid's container isn't recorded, so the layout is checked against the smallest
+// container it fits in: no layout with larger squares may fit in it, and it must fit
+// in the window. The layout is always computed for the grid's initial number of squares.
+func geometry(att *attempt.Attempt, tr *trace.Trace) error {
+	for _, e := range tr.Events {
+		g, ok := e.(trace.EventGridResize)
+		if !ok {
+			continue
+		}
+
+		if g.NumSquares != att.NumSquares {
+			return fmt.Errorf("grid is laid out for %d squares, attempt has %d", g.NumSquares, att.NumSquares)
+		}
+
+		if g.Cols <= 0 || g.SideLength <= 0 {
+			return fmt.Errorf("grid has %d columns of side length %v", g.Cols, g.SideLength)
+		}
+
+		rows := (att.NumSquares + g.Cols - 1) / g.Cols
+		w, h := float64(g.Cols)*g.SideLength, float64(rows)*g.SideLength
+
+		if w > g.WindowSize.X+positionTolerance || h > g.WindowSize.Y+positionTolerance {
+			return fmt.Errorf("grid of %vx%v doesn't fit window of %vx%v", w, h, g.WindowSize.X, g.WindowSize.Y)
+		}
+
+		if fit := grid.Fit(att.NumSquares, w, h); fit.SideLength > g.SideLength*(1+1e-6) {
+			return fmt.Errorf("grid has %d columns of side length %v, but %d columns of side length %v fit", g.Cols, g.SideLength, fit.Cols, fit.SideLength)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+const (
+	// hoverScale is how much squares grow when hovered.
+	hoverScale = 1.33
+	// positionTolerance accounts for pointer positions being rounded to whole pixels.
+	positionTolerance = 1
+	// shiftDuration is how long squares take to move to their new cell after a removal.
+	shiftDuration = 550 * time.Millisecond
+)
+
+type removal struct {
+	t     time.Duration
+	index int
+}
+
+// hits checks that every removal's pointer event lands on the removed square,
+// given the viewport and grid geometry in effect at that moment.
+func hits(_ *attempt.Attempt, tr *trace.Trace) error {
+	var grid trace.EventGridResize
+	var viewport trace.Viewport
+	var hasGrid, hasViewport bool
+	var recent []removal
+
+	for _, e := range tr.Events {
+		switch e := e.(type) {
+		case trace.EventViewport:
+			viewport, hasViewport = e.Viewport, true
+		case trace.EventGridResize:
+			grid, hasGrid = e, true
+		case trace.GameEventRemoveSquare:
+			pe := e.PointerEvent()
+			if pe == nil {
+				return errors.New("square removed without a pointer event")
+			}
+			if !hasGrid || grid.Cols <= 0 || grid.SideLength <= 0 {
+				return errors.New("square removed before grid layout was recorded")
+			}
+
+			p := trace.XY[float64]{X: float64(pe.Position.X), Y: float64(pe.Position.Y)}
+			if hasViewport && !inViewport(p, viewport) {
+				return fmt.Errorf("pointer at (%v, %v) is outside the visible viewport", p.X, p.Y)
+			}
+
+			// Squares are where they were when the pointer event happened,
+			// which may be a while before its removal was recorded.
+			for len(recent) > 0 && pe.T-recent[0].t > shiftDuration {
+				recent = recent[1:]
+			}
+
+			i := e.Square.Row*grid.Cols + e.Square.Col
+			if !hitsSquare(p, grid, i, recent) {
+				return fmt.Errorf("pointer at (%v, %v) misses square (%d, %d)", p.X, p.Y, e.Square.Row, e.Square.Col)
+			}
+
+			recent = append(recent, removal{t: e.T, index: i})
+		}
+	}
+
+	return nil
+}
+
+func inViewport(p trace.XY[float64], vp trace.Viewport) bool {
+	return p.X >= vp.Offset.X-positionTolerance && p.X <= vp.Offset.X+vp.Size.X+positionTolerance &&
+		p.Y >= vp.Offset.Y-positionTolerance && p.Y <= vp.Offset.Y+vp.Size.Y+positionTolerance
+}
+
+// hitsSquare reports whether p is on the square at index i. Squares which were
+// recently shifted by removals may still be on their way to their new cell,
+// so every cell the square passed through since is considered.
+func hitsSquare(p trace.XY[float64], grid trace.EventGridResize, i int, recent []removal) bool {
+	to := cellCenter(grid, i)
+	half := grid.SideLength * hoverScale / 2
+
+	if sweeps(p, to, to, half) {
+		return true
+	}
+
+	pos := i
+	for j := len(recent) - 1; j >= 0; j-- {
+		if recent[j].index > pos {
+			continue
+		}
+
+		pos++
+		from := cellCenter(grid, pos)
+		if sweeps(p, from, to, half) {
+			return true
+		}
+		to = from
+	}
+
+	return false
+}
+
+func cellCenter(grid trace.EventGridResize, i int) trace.XY[float64] {
+	row, col := i/grid.Cols, i%grid.Cols
+	return trace.XY[float64]{
+		X: grid.Anchor.X + (float64(col)+0.5)*grid.SideLength,
+		Y: grid.Anchor.Y + (float64(row)+0.5)*grid.SideLength,
+	}
+}
+
+// sweeps reports whether p is covered by a square of the given half side length
+// moving in a straight line between the centers a and b.
+func sweeps(p, a, b trace.XY[float64], half float64) bool {
+	half += positionTolerance
+
+	lo, hi := 0.0, 1.0
+	for _, axis := range [...]struct{ p, a, d float64 }{{p.X, a.X, b.X - a.X}, {p.Y, a.Y, b.Y - a.Y}} {
+		if axis.d == 0 {
+			if axis.p < axis.a-half || axis.p > axis.a+half {
+				return false
+			}
+			continue
+		}
+
+		t0, t1 := (axis.p-half-axis.a)/axis.d, (axis.p+half-axis.a)/axis.d
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+
+		lo, hi = max(lo, t0), min(hi, t1)
+	}
+
+	return lo <= hi
+}
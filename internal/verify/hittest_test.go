@@ -0,0 +1,185 @@
+package verify
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// testGrid is a grid of 6 columns of 60px squares in a 390x844 window, as the client lays it out.
+var testGrid = trace.EventGridResize{
+	GridResizeData: trace.GridResizeData{
+		Anchor:     trace.XY[float64]{X: 15, Y: 120},
+		SideLength: 60,
+		Cols:       6,
+		NumSquares: numSquares,
+	},
+	WindowSize: trace.XY[float64]{X: 390, Y: 844},
+}
+
+// click removes the square at (row, col) with a mouse click at (x, y), t milliseconds into the game.
+type click struct {
+	t        int
+	row, col int
+	x, y     uint16
+}
+
+// clickTrace records the clicks on testGrid, in the order they are given.
+func clickTrace(t *testing.T, clicks ...click) *trace.Trace {
+	t.Helper()
+
+	tr := &trace.Trace{
+		Events: trace.Events{
+			trace.EventViewport{Viewport: trace.Viewport{Size: trace.XY[float64]{X: 390, Y: 844}, Scale: 1}},
+			testGrid,
+		},
+		Pointers:          []trace.Pointer{{Type: "mouse", Primary: true, Move: true}},
+		FirstPointerEvent: trace.Timestamp(time.Duration(clicks[0].t) * time.Millisecond),
+	}
+
+	var payload []byte
+	prev := clicks[0].t
+	for i, c := range clicks {
+		tr.Events = append(tr.Events, trace.GameEventRemoveSquare{
+			Square:            trace.Square{Color: "var(--color-square-1)", Row: c.row, Col: c.col},
+			PointerEventIndex: i,
+			T:                 time.Duration(c.t) * time.Millisecond,
+		})
+
+		payload = append(payload, 0)
+		payload = binary.LittleEndian.AppendUint16(payload, c.x)
+		payload = binary.LittleEndian.AppendUint16(payload, c.y)
+		// Deltas are in units of 5 microseconds.
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(c.t-prev)*200)
+		prev = c.t
+	}
+
+	if err := tr.SetPointerEvents(payload); err != nil {
+		t.Fatalf("set pointer events: %v", err)
+	}
+
+	return tr
+}
+
+func TestHits(t *testing.T) {
+	// The center of the square at (0, 0) is at (45, 150), the one at (0, 1) at (105, 150).
+	// Hovered squares grow to 79.8px, so a click up to 39.9px away from the center, plus a pixel
+	// of tolerance, hits the square.
+	tests := []struct {
+		name   string
+		clicks []click
+		hit    bool
+	}{
+		{
+			name:   "center",
+			clicks: []click{{t: 1000, x: 45, y: 150}},
+			hit:    true,
+		},
+		{
+			name:   "edge of the hovered square",
+			clicks: []click{{t: 1000, x: 85, y: 110}},
+			hit:    true,
+		},
+		{
+			name:   "past the edge of the hovered square",
+			clicks: []click{{t: 1000, x: 87, y: 150}},
+			hit:    false,
+		},
+		{
+			name:   "another square",
+			clicks: []click{{t: 1000, row: 1, col: 2, x: 45, y: 150}},
+			hit:    false,
+		},
+		{
+			name:   "square still moving to its new cell",
+			clicks: []click{{t: 1000, x: 45, y: 150}, {t: 1200, x: 105, y: 150}},
+			hit:    true,
+		},
+		{
+			name:   "old cell of a square which finished moving",
+			clicks: []click{{t: 1000, x: 45, y: 150}, {t: 1600, x: 105, y: 150}},
+			hit:    false,
+		},
+		{
+			name: "square which moved through multiple cells",
+			// The square at (0, 2) moves to (0, 0) after the first two removals.
+			clicks: []click{{t: 1000, x: 45, y: 150}, {t: 1100, x: 45, y: 150}, {t: 1200, x: 165, y: 150}},
+			hit:    true,
+		},
+		{
+			name:   "outside the viewport",
+			clicks: []click{{t: 1000, x: 45, y: 900}},
+			hit:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hits(nil, clickTrace(t, tt.clicks...))
+			if tt.hit && err != nil {
+				t.Errorf("expected hit, got %v", err)
+			} else if !tt.hit && err == nil {
+				t.Error("expected miss")
+			}
+		})
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	tests := []struct {
+		name  string
+		grid  func(g *trace.EventGridResize)
+		valid bool
+	}{
+		{
+			name:  "client layout",
+			grid:  func(*trace.EventGridResize) {},
+			valid: true,
+		},
+		{
+			name: "grid larger than the window",
+			grid: func(g *trace.EventGridResize) {
+				g.WindowSize = trace.XY[float64]{X: 300, Y: 844}
+			},
+		},
+		{
+			name: "grid laid out for fewer squares",
+			grid: func(g *trace.EventGridResize) {
+				g.NumSquares = 12
+			},
+		},
+		{
+			name: "grid without columns",
+			grid: func(g *trace.EventGridResize) {
+				g.Cols = 0
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := testGrid
+			tt.grid(&g)
+
+			err := geometry(&attempt.Attempt{NumSquares: numSquares}, &trace.Trace{Events: trace.Events{g}})
+			if tt.valid && err != nil {
+				t.Errorf("expected valid layout, got %v", err)
+			} else if !tt.valid && err == nil {
+				t.Error("expected invalid layout")
+			}
+		})
+	}
+}
+
+func TestAttemptWithMovedPointer(t *testing.T) {
+	att, tr := loadSynthetic(t, "odd-one-out")
+	// The pointer event of the tenth removal is moved a square to the right.
+	tr.PointerEvents[10].Position.X += 60
+
+	if res := Attempt(att, tr); res.Verification != attempt.VerificationInvalid || !res.Heuristic {
+		t.Errorf("got %s (heuristic: %t), expected the moved pointer to miss", res.Verification, res.Heuristic)
+	}
+}
@@ -0,0 +1,50 @@
+package verify
+
+import (
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// Source is a stream of uniformly distributed numbers in [0, 1).
+type Source interface {
+	Float64() float64
+}
+
+// PlayMystery plays a Mystery game on a grid of numSquares squares using the numbers
+// from src. It returns the number of popped squares and whether the game was won.
+func PlayMystery(src Source, numSquares int) (popped int, won bool) {
+	for popped = 1; popped <= numSquares; popped++ {
+		if mysteryLoses(src, numSquares-popped) {
+			return popped, false
+		}
+	}
+
+	return numSquares, true
+}
+
+// mysteryLoses decides the fate of a game which has count squares left after a pop.
+func mysteryLoses(src Source, count int) bool {
+	return count > 1 && intn(src.Float64(), count+1) == count
+}
+
+func mystery(att *attempt.Attempt, tr *trace.Trace) (uint32, error) {
+	return replay(att, tr, mysteryGame{})
+}
+
+type mysteryGame struct{}
+
+func (mysteryGame) squares(*stream) board {
+	return unknownBoard(numSquares)
+}
+
+func (mysteryGame) progress(b *board, i int, r *stream) outcome {
+	b.remove(i)
+
+	if mysteryLoses(r, len(*b)) {
+		return outcomeLose
+	} else if len(*b) == 0 {
+		return outcomeWin
+	}
+
+	return outcomeContinue
+}
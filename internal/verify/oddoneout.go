@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+func oddOneOut(att *attempt.Attempt, tr *trace.Trace) (uint32, error) {
+	return replay(att, tr, oddOneOutGame{})
+}
+
+type oddOneOutGame struct{}
+
+func (oddOneOutGame) squares(r *stream) board {
+	return genOddOneOut(numColors, numSquares, r)
+}
+
+func (oddOneOutGame) progress(b *board, i int, r *stream) outcome {
+	color := b.remove(i)
+	count := len(*b)
+
+	if b.has(color) {
+		return outcomeLose
+	} else if count == 0 {
+		return outcomeWin
+	}
+
+	if count > 1 {
+		*b = genOddOneOut(numColors, count, r)
+	}
+
+	return outcomeContinue
+}
+
+// genOddOneOut is the exact counterpart of OddOneOut.genSquares in the client.
+func genOddOneOut(numColors, numSquares int, r *stream) board {
+	selection := make([]int, numColors)
+	for i := range selection {
+		selection[i] = i
+	}
+
+	var oddColor int
+	for len(selection) >= min(numColors, numSquares) {
+		i, last := intn(r.Float64(), len(selection)), len(selection)-1
+		selection[i], selection[last] = selection[last], selection[i]
+		oddColor = selection[last]
+		selection = selection[:last]
+	}
+
+	colors := make(board, numSquares)
+	for i := range colors {
+		colors[i] = selection[intn(r.Float64(), len(selection))]
+	}
+
+	colors[intn(r.Float64(), len(colors))] = oddColor
+
+	return colors
+}
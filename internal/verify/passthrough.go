@@ -0,0 +1,38 @@
+package verify
+
+import (
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// passthrough can't replay square colors, as they come from Math.random,
+// so only the removal order is checked here. See passthroughTiming.
+func passthrough(att *attempt.Attempt, tr *trace.Trace) (uint32, error) {
+	return replay(att, tr, passthroughGame{})
+}
+
+// passthroughTiming checks the timing of the removals of passthrough attempts,
+// which is the only evidence there is against them.
+func passthroughTiming(att *attempt.Attempt, tr *trace.Trace) error {
+	if att.Gamemode != attempt.GamemodePassthrough {
+		return nil
+	}
+
+	return timing(tr)
+}
+
+type passthroughGame struct{}
+
+func (passthroughGame) squares(*stream) board {
+	return unknownBoard(numSquares)
+}
+
+func (passthroughGame) progress(b *board, i int, _ *stream) outcome {
+	b.remove(i)
+
+	if len(*b) == 0 {
+		return outcomeWin
+	}
+
+	return outcomeContinue
+}
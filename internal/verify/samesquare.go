@@ -0,0 +1,47 @@
+package verify
+
+import (
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+func sameSquare(att *attempt.Attempt, tr *trace.Trace) (uint32, error) {
+	return replay(att, tr, &sameSquareGame{lastColor: unknownColor})
+}
+
+type sameSquareGame struct {
+	lastColor int
+}
+
+func (*sameSquareGame) squares(r *stream) board {
+	b := make(board, numSquares)
+	for i := range b {
+		b[i] = intn(r.Float64(), numColors)
+	}
+
+	return b
+}
+
+// progress enforces the maximal sequence rule: the player may switch colors
+// only after all squares of the previous color were removed.
+func (g *sameSquareGame) progress(b *board, i int, _ *stream) outcome {
+	color := b.remove(i)
+
+	res := outcomeContinue
+	if len(*b) == 0 {
+		res = outcomeWin
+	}
+
+	if g.lastColor == unknownColor || g.lastColor == color {
+		g.lastColor = color
+		return res
+	}
+
+	if b.has(g.lastColor) {
+		return outcomeLose
+	}
+
+	g.lastColor = color
+
+	return res
+}
@@ -0,0 +1,203 @@
+// Generates the synthetic attempts in this directory. They are not recorded by a browser:
+// the gamemodes are transliterated from src/game/gamemode and run on the client's WASM rand
+// from src/rand.ts, while a player tapping on a phone is simulated with a seeded generator,
+// which picks the intervals between taps, the tap positions and the lag of the pointer handlers.
+//
+// Usage: node gen.mjs <gamemode>[-lose] '<rand state>' <seed> > <gamemode>.json
+// The rand state is the JSON of the attempt's randState, e.g. '{"key": [1, 2], "mask": 3, "off": 4}'.
+
+import fs from 'node:fs'
+
+const src = fs.readFileSync(new URL('../../../../src/rand.ts', import.meta.url), 'utf8')
+const bytes = src
+  .match(/new Uint8Array\(\[([\s\S]*?)\]\)/)[1]
+  .split(',')
+  .map((s) => s.trim())
+  .filter(Boolean)
+  .map(Number)
+const wasm = new WebAssembly.Instance(new WebAssembly.Module(new Uint8Array(bytes))).exports.rand
+
+class Rand {
+  constructor({ key, mask, off }) {
+    this.key = key
+    this.mask = mask
+    this.off = off >>> 0
+  }
+
+  next() {
+    const v = wasm(this.mask, this.off, this.key[0], this.key[1])
+    this.off = (this.off + 1) >>> 0
+    return v
+  }
+}
+
+// mulberry32 stands in for the player and for Math.random.
+const mulberry32 = (seed) => () => {
+  seed = (seed + 0x6d2b79f5) | 0
+  let t = Math.imul(seed ^ (seed >>> 15), 1 | seed)
+  t = (t + Math.imul(t ^ (t >>> 7), 61 | t)) ^ t
+  return ((t ^ (t >>> 14)) >>> 0) / 4294967296
+}
+
+const intn = (f, n) => Math.floor(f * n)
+// Browsers coarsen performance.now to 100 microseconds.
+const coarsen = (t) => Math.round(t * 10) / 10
+
+const NUM_COLORS = 5
+const NUM_SQUARES = 48
+const COLS = 6
+const SIDE = 60
+const ANCHOR = [15, 120.5]
+const WINDOW = [390, 844]
+
+// Transliterated from OddOneOut.genSquares.
+function genOddOneOut(numColors, numSquares, rand) {
+  const colorSelection = Array.from({ length: numColors }, (_, i) => i)
+  let oddColor
+  while (colorSelection.length >= Math.min(numColors, numSquares)) {
+    const i = intn(rand.next(), colorSelection.length)
+    ;[colorSelection[i], colorSelection[colorSelection.length - 1]] = [colorSelection[colorSelection.length - 1], colorSelection[i]]
+    oddColor = colorSelection.pop()
+  }
+  const colors = Array.from({ length: numSquares }, () => colorSelection[intn(rand.next(), colorSelection.length)])
+  colors[intn(rand.next(), colors.length)] = oddColor
+  return colors
+}
+
+const [variant, randState, seed] = process.argv.slice(2)
+const gamemode = variant.replace(/-lose$/, '')
+const cfg = JSON.parse(randState)
+const rand = new Rand(cfg)
+const player = mulberry32(Number(seed))
+
+let colors
+if (gamemode === 'odd-one-out') colors = genOddOneOut(NUM_COLORS, NUM_SQUARES, rand)
+else if (gamemode === 'same-square') colors = Array.from({ length: NUM_SQUARES }, () => intn(rand.next(), NUM_COLORS))
+else colors = Array.from({ length: NUM_SQUARES }, () => intn(player(), NUM_COLORS))
+
+// removed holds the layout indices of the removed squares, with their colors.
+const removed = []
+let lastColor
+let state = 'continue'
+
+while (state === 'continue') {
+  let i
+  if (gamemode === 'odd-one-out') i = colors.findIndex((c) => colors.filter((d) => d === c).length === 1)
+  else if (gamemode === 'same-square' && variant.endsWith('-lose') && removed.length === 2) i = colors.findIndex((c) => c !== lastColor)
+  else if (gamemode === 'same-square') i = lastColor !== undefined && colors.includes(lastColor) ? colors.indexOf(lastColor) : intn(player(), colors.length)
+  else i = intn(player(), colors.length)
+
+  const c = colors[i]
+  removed.push([i, c])
+  colors.splice(i, 1)
+  const count = colors.length
+
+  if (gamemode === 'odd-one-out') {
+    state = colors.includes(c) ? 'lose' : count === 0 ? 'win' : 'continue'
+    if (state === 'continue' && count > 1) colors = genOddOneOut(NUM_COLORS, count, rand)
+  } else if (gamemode === 'same-square') {
+    state = count === 0 ? 'win' : 'continue'
+    if (lastColor !== undefined && lastColor !== c && colors.includes(lastColor)) state = 'lose'
+    else lastColor = c
+  } else if (gamemode === 'random') {
+    const lose = count > 1 && count === intn(rand.next(), count + 1)
+    state = lose ? 'lose' : count > 0 ? 'continue' : 'win'
+  } else {
+    state = count === 0 ? 'win' : 'continue'
+  }
+}
+
+const events = [
+  { type: 'viewport', time: 0.1, data: { size: WINDOW, off: [0, 0], scale: 1 } },
+  { type: 'orientationChange', time: 0.3, data: { type: 'portrait-primary', angle: 0 } },
+  { type: 'theme', time: 1.7, name: 'candy' },
+  { type: 'gridResize', time: 2.4, data: { anchor: ANCHOR, sideLength: SIDE, cols: COLS, numSquares: NUM_SQUARES }, windowSize: WINDOW },
+  { type: 'prepare', time: 10.2, animation: 'short' },
+]
+
+// The player leaves the tab once, in longer games.
+const pauseAfter = removed.length > 20 ? 20 : -1
+const payload = []
+let tap = coarsen(900 + 500 * player())
+let prevTap = tap
+const firstTap = tap
+let firstRemoval, lastRemoval
+let paused = 0
+
+removed.forEach(([i, c], k) => {
+  // Pointer handlers run a little after the pointer event, and rarely much later.
+  const lag = player() < 0.05 ? 60 + 80 * player() : 3 + 25 * player()
+  const t = coarsen(tap + lag)
+  firstRemoval ??= t
+  lastRemoval = t
+
+  events.push({
+    type: 'removeSquare',
+    time: t,
+    square: { color: `var(--color-square-${c + 1})`, row: Math.floor(i / COLS), col: i % COLS },
+    pointerEventIndex: k,
+  })
+
+  // Taps land around the center of the square, in the bounds of the finger.
+  const x = Math.round(ANCHOR[0] + ((i % COLS) + 0.5) * SIDE + (player() - 0.5) * 36)
+  const y = Math.round(ANCHOR[1] + (Math.floor(i / COLS) + 0.5) * SIDE + (player() - 0.5) * 36)
+  const b = Buffer.alloc(9)
+  b.writeUInt8(0, 0)
+  b.writeUInt16LE(x, 1)
+  b.writeUInt16LE(y, 3)
+  // Deltas are in units of 5 microseconds.
+  b.writeUInt32LE(Math.round((tap - prevTap) * 200) >>> 0, 5)
+  payload.push(b)
+  prevTap = tap
+
+  let next = t + 250 + 450 * player() ** 2
+  if (player() < 0.1) next += 800 + 1000 * player()
+  if (k === pauseAfter) {
+    const pause = coarsen(t + 400 + 300 * player())
+    const resume = coarsen(pause + 2000 + 2000 * player())
+    events.push({ type: 'pause', time: pause, pause: 'visibility' }, { type: 'resume', time: resume, pause: 'visibility' })
+    paused = resume - pause
+    next = resume + 500 + 300 * player()
+  }
+  tap = coarsen(next)
+})
+
+const timeOrigin = 1792240345123.4
+const trace = {
+  metadata: { maxTouchPoints: 5, primaryPointer: 2, anyPointer: 2 },
+  events,
+  pointers: [{ type: 'touch', size: [24.5, 24.5], primary: true, move: false }],
+  timeOrigin,
+  firstPointerEventTime: firstTap,
+}
+const attempt = {
+  gamemode,
+  startedAt: new Date(timeOrigin + firstRemoval).toISOString(),
+  // Claimed without the time the game was paused for.
+  duration: coarsen(lastRemoval - firstRemoval - paused),
+  isWin: state === 'win',
+  numSquares: NUM_SQUARES,
+  randState: { mask: cfg.mask, key: cfg.key, off: cfg.off },
+}
+
+const line = (v) => JSON.stringify(v).replace(/("(?:[^"\\]|\\.)*")|([:,])/g, (m, str, sep) => str ?? `${sep} `)
+const pointerEvents = Buffer.concat(payload).toString('base64')
+process.stdout.write(
+  [
+    '{',
+    `\t"attempt": ${line(attempt)},`,
+    '\t"trace": {',
+    `\t\t"metadata": ${line(trace.metadata)},`,
+    '\t\t"events": [',
+    trace.events.map((e) => `\t\t\t${line(e)}`).join(',\n'),
+    '\t\t],',
+    `\t\t"pointers": ${line(trace.pointers)},`,
+    `\t\t"timeOrigin": ${trace.timeOrigin},`,
+    `\t\t"firstPointerEventTime": ${trace.firstPointerEventTime}`,
+    '\t},',
+    `\t"pointerEvents": "${pointerEvents}"`,
+    '}',
+    '',
+  ].join('\n'),
+)
+console.error(variant, state, removed.length, 'squares, rand ends at', rand.off)
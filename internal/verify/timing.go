@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+const (
+	// minClickInterval is the shortest possible time between two clicks of a mouse.
+	minClickInterval = 30 * time.Millisecond
+	// minTapInterval is the shortest possible time between two taps. Multiple fingers
+	// can tap almost at the same time, so this is mostly a sanity check.
+	minTapInterval = 4 * time.Millisecond
+	// burstLength taps must last at least burstLength*minBurstInterval,
+	// no matter how many fingers are used.
+	burstLength      = 8
+	minBurstInterval = 40 * time.Millisecond
+	// maxThroughput is a generous upper bound, in bits per second, of the rate at which
+	// a mouse can be pointed at a target, as given by the Shannon formulation of Fitts' law.
+	maxThroughput = 20
+	// maxHandlerLag is the longest allowed time between a pointer event and the removal
+	// recorded by its handler. Pointer events are stamped before their handlers run,
+	// which on busy or low-end devices can take a while.
+	maxHandlerLag = 500 * time.Millisecond
+	// clockSkew is how much later than its removal a pointer event may seem to happen.
+	// Pointer events and removals are stamped by different clocks of limited precision.
+	clockSkew = 5 * time.Millisecond
+)
+
+type tap struct {
+	t     time.Duration
+	pos   trace.XY[float64]
+	mouse bool
+	side  float64
+}
+
+// timing checks whether the removals in the trace could have been done by a human.
+func timing(tr *trace.Trace) error {
+	var taps []tap
+	var side float64
+
+	for _, e := range tr.Events {
+		switch e := e.(type) {
+		case trace.EventGridResize:
+			side = e.SideLength
+		case trace.GameEventRemoveSquare:
+			pe := e.PointerEvent()
+			if pe == nil || pe.Pointer() == nil {
+				return errors.New("square removed without a pointer event")
+			}
+
+			if lag := e.T - pe.T; lag < -clockSkew || lag > maxHandlerLag {
+				return fmt.Errorf("removal at %v has pointer event at %v", e.T, pe.T)
+			}
+
+			taps = append(taps, tap{
+				t:     pe.T,
+				pos:   trace.XY[float64]{X: float64(pe.Position.X), Y: float64(pe.Position.Y)},
+				mouse: pe.Pointer().Type == "mouse",
+				side:  side,
+			})
+		}
+	}
+
+	if len(taps) == 0 {
+		return errors.New("no squares removed")
+	}
+
+	for i := 1; i < len(taps); i++ {
+		prev, curr := taps[i-1], taps[i]
+		dt := curr.t - prev.t
+
+		minInterval := minTapInterval
+		if prev.mouse && curr.mouse {
+			minInterval = max(minClickInterval, fittsTime(prev.pos, curr.pos, curr.side))
+		}
+
+		if dt < minInterval {
+			return fmt.Errorf("removal %d came %v after the previous one, at least %v needed", i, dt, minInterval)
+		}
+
+		if i >= burstLength {
+			if span := curr.t - taps[i-burstLength].t; span < burstLength*minBurstInterval {
+				return fmt.Errorf("%d removals done in %v", burstLength, span)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fittsTime is the minimum time required to move a mouse between two targets.
+func fittsTime(from, to trace.XY[float64], width float64) time.Duration {
+	if width <= 0 {
+		return 0
+	}
+
+	dist := math.Hypot(to.X-from.X, to.Y-from.Y)
+	id := math.Log2(dist/width + 1)
+
+	return time.Duration(id / maxThroughput * float64(time.Second))
+}
@@ -0,0 +1,254 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/handler/sessionrand"
+	"github.com/tmaxmax/popthegrid/internal/srand"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// Result is the outcome of verifying an attempt.
+type Result struct {
+	Verification attempt.Verification
+	// Reason describes why the attempt is invalid.
+	Reason string
+	// Heuristic tells whether the attempt was found invalid by a heuristic, which
+	// may reject genuine attempts, instead of by a contradiction in its trace.
+	Heuristic bool
+	// RandEnd is the offset of the attempt's random stream after its last pop.
+	RandEnd uint32
+}
+
+type verifier func(att *attempt.Attempt, tr *trace.Trace) (randEnd uint32, err error)
+
+var verifiers = map[attempt.Gamemode]verifier{
+	attempt.GamemodeOddOneOut:   oddOneOut,
+	attempt.GamemodeSameSquare:  sameSquare,
+	attempt.GamemodeRandom:      mystery,
+	attempt.GamemodePassthrough: passthrough,
+}
+
+// checks run for every attempt after its gamemode's verifier.
+var checks = []func(att *attempt.Attempt, tr *trace.Trace) error{
+	geometry,
+}
+
+// heuristics run after the checks. They reject attempts which are unlikely
+// to have been played by a human, though they could have been.
+var heuristics = []func(att *attempt.Attempt, tr *trace.Trace) error{
+	hits,
+	checkDuration,
+	passthroughTiming,
+}
+
+// Attempt checks whether the given trace is a plausible recording of the given attempt.
+// Attempts of gamemodes which can't be verified yet are left pending.
+func Attempt(att *attempt.Attempt, tr *trace.Trace) Result {
+	v, ok := verifiers[att.Gamemode]
+	if !ok {
+		return Result{Verification: attempt.VerificationPending, RandEnd: att.RandState.Offset}
+	}
+
+	end, err := v(att, tr)
+	for _, check := range checks {
+		if err != nil {
+			break
+		}
+
+		err = check(att, tr)
+	}
+	if err != nil {
+		return Invalid(err)
+	}
+
+	for _, check := range heuristics {
+		if err := check(att, tr); err != nil {
+			res := Invalid(err)
+			res.Heuristic = true
+			return res
+		}
+	}
+
+	return Result{Verification: attempt.VerificationValid, RandEnd: end}
+}
+
+// RandEnd replays the attempt to find the offset of its random stream after its last pop,
+// without running the other checks.
+func RandEnd(att *attempt.Attempt, tr *trace.Trace) (uint32, error) {
+	v, ok := verifiers[att.Gamemode]
+	if !ok {
+		return att.RandState.Offset, nil
+	}
+
+	return v(att, tr)
+}
+
+func Invalid(err error) Result {
+	return Result{Verification: attempt.VerificationInvalid, Reason: err.Error()}
+}
+
+const (
+	numColors  = trace.NumColors
+	numSquares = 48
+)
+
+// stream mirrors the client's Rand: the offset wraps around
+// without ever touching the mask.
+type stream struct {
+	rand sessionrand.Rand
+	off  uint32
+}
+
+func (s *stream) Float64() float64 {
+	src := s.rand.Source(s.off)
+	s.off++
+	return srand.Float64(src.Cnt, src.Key)
+}
+
+func intn(f float64, n int) int {
+	return int(math.Floor(f * float64(n)))
+}
+
+type outcome int
+
+const (
+	outcomeContinue outcome = iota
+	outcomeWin
+	outcomeLose
+)
+
+// unknownColor marks squares whose colors are not generated from the random stream.
+const unknownColor = -1
+
+// board holds the color indices of the grid's active squares, in layout order.
+type board []int
+
+func unknownBoard(n int) board {
+	b := make(board, n)
+	for i := range b {
+		b[i] = unknownColor
+	}
+
+	return b
+}
+
+func (b *board) remove(i int) int {
+	c := (*b)[i]
+	*b = append((*b)[:i], (*b)[i+1:]...)
+	return c
+}
+
+func (b board) has(color int) bool {
+	for _, c := range b {
+		if c == color {
+			return true
+		}
+	}
+
+	return false
+}
+
+type game interface {
+	squares(r *stream) board
+	progress(b *board, i int, r *stream) outcome
+}
+
+var games = map[attempt.Gamemode]func() game{
+	attempt.GamemodeOddOneOut:   func() game { return oddOneOutGame{} },
+	attempt.GamemodeSameSquare:  func() game { return &sameSquareGame{lastColor: unknownColor} },
+	attempt.GamemodeRandom:      func() game { return mysteryGame{} },
+	attempt.GamemodePassthrough: func() game { return passthroughGame{} },
+}
+
+// Boards replays the attempt and returns the color indices of the grid's squares, in layout order,
+// at the start of the game and after each removal. Colors which aren't generated from the random
+// stream are -1: the client picks them with Math.random, or the gamemode doesn't color squares.
+func Boards(att *attempt.Attempt, tr *trace.Trace) ([][]int, error) {
+	newGame, ok := games[att.Gamemode]
+	if !ok {
+		return nil, fmt.Errorf("gamemode %q can't be replayed", att.Gamemode)
+	}
+
+	var boards [][]int
+	_, err := play(att, tr, newGame(), func(b board) {
+		boards = append(boards, append([]int(nil), b...))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return boards, nil
+}
+
+// replay plays the game the trace recorded, checking every removed square
+// against the board the game would have at that moment.
+func replay(att *attempt.Attempt, tr *trace.Trace, g game) (uint32, error) {
+	return play(att, tr, g, func(board) {})
+}
+
+// play is replay, which also calls visit with the board at the start of the game and after each removal.
+func play(att *attempt.Attempt, tr *trace.Trace, g game, visit func(b board)) (uint32, error) {
+	if att.NumSquares != numSquares {
+		return 0, fmt.Errorf("grid has %d squares, expected %d", att.NumSquares, numSquares)
+	}
+
+	r := &stream{rand: att.RandState.Rand, off: att.RandState.Offset}
+	b := g.squares(r)
+	res := outcomeContinue
+	visit(b)
+	cols := 0
+
+	for _, e := range tr.Events {
+		switch e := e.(type) {
+		case trace.EventGridResize:
+			cols = e.Cols
+		case trace.GameEventRemoveSquare:
+			if res != outcomeContinue {
+				return 0, errors.New("square removed after game ended")
+			}
+			if cols <= 0 {
+				return 0, errors.New("square removed before grid layout was recorded")
+			}
+
+			sq := e.Square
+			i := sq.Row*cols + sq.Col
+			if sq.Row < 0 || sq.Col < 0 || sq.Col >= cols || i >= len(b) {
+				return 0, fmt.Errorf("square (%d, %d) is not in the grid", sq.Row, sq.Col)
+			}
+
+			color, ok := sq.ColorIndex()
+			if !ok {
+				return 0, fmt.Errorf("invalid square color %q", sq.Color)
+			}
+
+			if b[i] == unknownColor {
+				b[i] = color
+			} else if b[i] != color {
+				return 0, fmt.Errorf("square (%d, %d) has color %d, trace claims %d", sq.Row, sq.Col, b[i], color)
+			}
+
+			res = g.progress(&b, i, r)
+			visit(b)
+		}
+	}
+
+	var kind attempt.Kind
+	switch res {
+	case outcomeWin:
+		kind = attempt.Win
+	case outcomeLose:
+		kind = attempt.Lose
+	default:
+		return 0, errors.New("trace ends before game ended")
+	}
+
+	if kind != att.Kind {
+		return 0, fmt.Errorf("attempt is %s, replay is %s", att.Kind, kind)
+	}
+
+	return r.off, nil
+}
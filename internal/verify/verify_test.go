@@ -0,0 +1,279 @@
+package verify
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/handler/sessionrand"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// loadSynthetic reads a synthetic attempt from testdata, in the submission format.
+// These aren't recorded by a browser: testdata/synthetic/gen.mjs plays the gamemodes
+// transliterated from the client with its rand, tapping the squares of a 6-column grid
+// of 60px squares on a phone at a simulated player's pace.
+func loadSynthetic(t *testing.T, name string) (*attempt.Attempt, *trace.Trace) {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/synthetic/" + name + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rec struct {
+		Attempt       attempt.Attempt `json:"attempt"`
+		Trace         trace.Trace     `json:"trace"`
+		PointerEvents []byte          `json:"pointerEvents"`
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("parse synthetic attempt: %v", err)
+	}
+	if err := rec.Trace.SetPointerEvents(rec.PointerEvents); err != nil {
+		t.Fatalf("set pointer events: %v", err)
+	}
+
+	return &rec.Attempt, &rec.Trace
+}
+
+// recolor changes the color of the nth removed square.
+func recolor(n int, color string) func(*attempt.Attempt, *trace.Trace) {
+	return func(_ *attempt.Attempt, tr *trace.Trace) {
+		for i, e := range tr.Events {
+			if rs, ok := e.(trace.GameEventRemoveSquare); ok {
+				if n == 0 {
+					rs.Square.Color = color
+					tr.Events[i] = rs
+					return
+				}
+				n--
+			}
+		}
+	}
+}
+
+// delayRemovals makes every removal happen d after its pointer event.
+func delayRemovals(d time.Duration) func(*attempt.Attempt, *trace.Trace) {
+	return func(_ *attempt.Attempt, tr *trace.Trace) {
+		for i, e := range tr.Events {
+			if rs, ok := e.(trace.GameEventRemoveSquare); ok {
+				rs.T = rs.PointerEvent().T + d
+				tr.Events[i] = rs
+			}
+		}
+	}
+}
+
+func TestAttemptSynthetic(t *testing.T) {
+	tests := []struct {
+		name      string
+		synthetic string
+		tamper    func(*attempt.Attempt, *trace.Trace)
+		// verification is the expected result. Valid attempts are expected
+		// to be of the given kind and to end their random stream at randEnd,
+		// invalid ones to be rejected by a heuristic if heuristic is set.
+		verification attempt.Verification
+		heuristic    bool
+		kind         attempt.Kind
+		randEnd      uint32
+	}{
+		{
+			name:         "odd-one-out win",
+			synthetic:    "odd-one-out",
+			verification: attempt.VerificationValid,
+			kind:         attempt.Win,
+			randEnd:      1292,
+		},
+		{
+			name:         "odd-one-out with a recolored square",
+			synthetic:    "odd-one-out",
+			tamper:       recolor(10, "var(--color-square-1)"),
+			verification: attempt.VerificationInvalid,
+		},
+		{
+			name:      "odd-one-out with another stream",
+			synthetic: "odd-one-out",
+			tamper: func(att *attempt.Attempt, _ *trace.Trace) {
+				att.RandState.Offset++
+			},
+			verification: attempt.VerificationInvalid,
+		},
+		{
+			name:         "same-square win across the stream's wraparound",
+			synthetic:    "same-square",
+			verification: attempt.VerificationValid,
+			kind:         attempt.Win,
+			randEnd:      42,
+		},
+		{
+			name:         "same-square lost by switching colors too early",
+			synthetic:    "same-square-lose",
+			verification: attempt.VerificationValid,
+			kind:         attempt.Lose,
+			randEnd:      42,
+		},
+		{
+			name:      "same-square lost by switching colors too early, claimed as a win",
+			synthetic: "same-square-lose",
+			tamper: func(att *attempt.Attempt, _ *trace.Trace) {
+				att.Kind = attempt.Win
+			},
+			verification: attempt.VerificationInvalid,
+		},
+		{
+			name:         "same-square with a recolored square",
+			synthetic:    "same-square",
+			tamper:       recolor(0, "var(--color-square-1)"),
+			verification: attempt.VerificationInvalid,
+		},
+		{
+			name:         "mystery loss",
+			synthetic:    "random",
+			verification: attempt.VerificationValid,
+			kind:         attempt.Lose,
+			randEnd:      1018,
+		},
+		{
+			name:         "mystery win",
+			synthetic:    "random-win",
+			verification: attempt.VerificationValid,
+			kind:         attempt.Win,
+			randEnd:      1085,
+		},
+		{
+			name:      "mystery loss claimed as a win",
+			synthetic: "random",
+			tamper: func(att *attempt.Attempt, _ *trace.Trace) {
+				att.Kind = attempt.Win
+			},
+			verification: attempt.VerificationInvalid,
+		},
+		{
+			name:         "passthrough win",
+			synthetic:    "passthrough",
+			verification: attempt.VerificationValid,
+			kind:         attempt.Win,
+			randEnd:      5,
+		},
+		{
+			name:      "passthrough removals 10ms apart",
+			synthetic: "passthrough",
+			tamper: func(_ *attempt.Attempt, tr *trace.Trace) {
+				for i := range tr.PointerEvents {
+					tr.PointerEvents[i].T = time.Second + time.Duration(i)*10*time.Millisecond
+				}
+				for i, e := range tr.Events {
+					if rs, ok := e.(trace.GameEventRemoveSquare); ok {
+						rs.T = rs.PointerEvent().T
+						tr.Events[i] = rs
+					}
+				}
+			},
+			verification: attempt.VerificationInvalid,
+			heuristic:    true,
+		},
+		{
+			name:         "passthrough removals trailing their pointer events by 100ms",
+			synthetic:    "passthrough",
+			tamper:       delayRemovals(100 * time.Millisecond),
+			verification: attempt.VerificationValid,
+			kind:         attempt.Win,
+			randEnd:      5,
+		},
+		{
+			name:         "passthrough removals trailing their pointer events by a second",
+			synthetic:    "passthrough",
+			tamper:       delayRemovals(time.Second),
+			verification: attempt.VerificationInvalid,
+			heuristic:    true,
+		},
+		{
+			name:         "passthrough removals preceding their pointer events",
+			synthetic:    "passthrough",
+			tamper:       delayRemovals(-50 * time.Millisecond),
+			verification: attempt.VerificationInvalid,
+			heuristic:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			att, tr := loadSynthetic(t, tt.synthetic)
+			if tt.tamper != nil {
+				tt.tamper(att, tr)
+			}
+
+			res := Attempt(att, tr)
+			if res.Verification != tt.verification {
+				t.Fatalf("got %s (%s), expected %s", res.Verification, res.Reason, tt.verification)
+			} else if res.Verification != attempt.VerificationValid {
+				if res.Heuristic != tt.heuristic {
+					t.Errorf("rejected by a heuristic: %t, expected %t (%s)", res.Heuristic, tt.heuristic, res.Reason)
+				}
+				return
+			}
+
+			if att.Kind != tt.kind {
+				t.Errorf("attempt is %s, expected %s", att.Kind, tt.kind)
+			}
+			if res.RandEnd != tt.randEnd {
+				t.Errorf("random stream ends at %d, expected %d", res.RandEnd, tt.randEnd)
+			}
+		})
+	}
+}
+
+func TestGenOddOneOutMatchesClient(t *testing.T) {
+	// Generated by the transliteration of OddOneOut.genSquares in testdata/synthetic/gen.mjs, on the client's rand,
+	// from a stream starting right before the offset wraps around.
+	r := sessionrand.Rand{Mask: 305419896, Key: [2]uint32{2712847316, 1511375289}}
+	const off = 4294967295
+
+	tests := []struct {
+		numSquares int
+		colors     board
+		end        uint32
+	}{
+		{48, board{2, 4, 3, 2, 4, 3, 0, 3, 3, 4, 1, 1, 3, 2, 2, 3, 3, 1, 1, 4, 1, 3, 3, 2, 2, 4, 2, 4, 2, 2, 4, 4, 1, 2, 4, 1, 2, 4, 3, 4, 4, 3, 3, 2, 2, 1, 2, 4}, 49},
+		{5, board{2, 4, 3, 0, 4}, 6},
+		// Fewer squares than colors take out more colors.
+		{3, board{1, 1, 4}, 6},
+		{2, board{3, 1}, 6},
+	}
+
+	for _, tt := range tests {
+		s := &stream{rand: r, off: off}
+		colors := genOddOneOut(numColors, tt.numSquares, s)
+
+		if !reflect.DeepEqual(colors, tt.colors) {
+			t.Errorf("%d squares: got %v, expected %v", tt.numSquares, colors, tt.colors)
+		}
+		if s.off != tt.end {
+			t.Errorf("%d squares: stream ends at %d, expected %d", tt.numSquares, s.off, tt.end)
+		}
+	}
+}
+
+func TestPlayMysteryMatchesClient(t *testing.T) {
+	r := sessionrand.Rand{Mask: 2882400001, Key: [2]uint32{1779033703, 3144134277}}
+
+	// The outcomes of the synthetic mystery attempts.
+	tests := []struct {
+		off    uint32
+		popped int
+		won    bool
+	}{
+		{1000, 18, false},
+		{1039, 48, true},
+	}
+
+	for _, tt := range tests {
+		popped, won := PlayMystery(&stream{rand: r, off: tt.off}, numSquares)
+		if popped != tt.popped || won != tt.won {
+			t.Errorf("offset %d: popped %d squares and won %t, expected %d and %t", tt.off, popped, won, tt.popped, tt.won)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package verify
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/tmaxmax/popthegrid/internal/attempt"
+	"github.com/tmaxmax/popthegrid/internal/trace"
+)
+
+// Pending is an attempt waiting to be verified.
+type Pending struct {
+	ID      uuid.UUID
+	Attempt attempt.Attempt
+	// Trace is the stored trace, in the format read by trace.Trace.Scan.
+	Trace []byte
+}
+
+type Repository interface {
+	// ClaimPending returns at most limit pending attempts, which won't be
+	// returned again until the lease expires or their verification is set.
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]Pending, error)
+	SetVerification(ctx context.Context, id uuid.UUID, res Result) error
+}
+
+const (
+	lease        = time.Minute
+	claimFactor  = 4
+	maxRetries   = 5
+	retryDelay   = 100 * time.Millisecond
+	writeTimeout = 10 * time.Second
+)
+
+// Worker verifies pending attempts in the background.
+type Worker struct {
+	Repository Repository
+	Logger     *slog.Logger
+	// Concurrency is the number of attempts verified at once. Defaults to GOMAXPROCS.
+	Concurrency int
+	// Interval is the time to wait for new attempts after all pending ones were verified.
+	// Defaults to 5 seconds.
+	Interval time.Duration
+}
+
+// Run verifies attempts until the context is canceled. Attempts which are being
+// verified at that moment have their result saved before Run returns.
+func (w Worker) Run(ctx context.Context) error {
+	concurrency := cmp.Or(w.Concurrency, runtime.GOMAXPROCS(0))
+	interval := cmp.Or(w.Interval, 5*time.Second)
+	limit := concurrency * claimFactor
+
+	jobs := make(chan Pending)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for range concurrency {
+		wg.Go(func() {
+			for p := range jobs {
+				w.verify(ctx, p)
+			}
+		})
+	}
+	defer close(jobs)
+
+	for {
+		var claimed []Pending
+		err := retry(ctx, func() (err error) {
+			claimed, err = w.Repository.ClaimPending(ctx, limit, lease)
+			return err
+		})
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			w.Logger.ErrorContext(ctx, "claim pending attempts", "err", err)
+		}
+
+		for _, p := range claimed {
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		if len(claimed) == limit {
+			continue
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (w Worker) verify(ctx context.Context, p Pending) {
+	var res Result
+
+	var tr trace.Trace
+	if err := tr.Scan(p.Trace); err != nil {
+		res = Invalid(fmt.Errorf("decode trace: %w", err))
+	} else {
+		res = Attempt(&p.Attempt, &tr)
+	}
+
+	// Save the result even if the worker is stopping, so the work isn't lost.
+	wctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+
+	if err := retry(wctx, func() error { return w.Repository.SetVerification(wctx, p.ID, res) }); err != nil {
+		w.Logger.ErrorContext(ctx, "save verification", "err", err, "id", p.ID, "result", res)
+	}
+}
+
+// retry calls f until it succeeds, backing off exponentially. All errors
+// returned by the repository are assumed to be transient (e.g. a busy database).
+func retry(ctx context.Context, f func() error) error {
+	delay := retryDelay
+
+	var err error
+	for range maxRetries {
+		if err = f(); err == nil {
+			return nil
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+
+		delay *= 2
+	}
+
+	return err
+}
@@ -0,0 +1 @@
+alter table attempts drop column verification_reason;
@@ -0,0 +1 @@
+alter table attempts add column verification_reason text;
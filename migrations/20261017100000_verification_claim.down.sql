@@ -0,0 +1,3 @@
+drop index attempts_pending;
+
+alter table attempts drop column verification_claimed_at;
@@ -0,0 +1,3 @@
+alter table attempts add column verification_claimed_at timestamp;
+
+create index attempts_pending on attempts (created_at) where verification = 'PENDING';
@@ -0,0 +1 @@
+drop table rand_windows;
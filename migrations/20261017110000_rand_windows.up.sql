@@ -0,0 +1,10 @@
+-- Ranges [start_offset, end_offset) of signed random streams consumed by attempts.
+create table rand_windows (
+    mask integer not null,
+    key_hi integer not null,
+    key_lo integer not null,
+    start_offset integer not null,
+    end_offset integer not null check (end_offset > start_offset),
+    attempt_id uuid not null unique references attempts,
+    primary key (mask, key_hi, key_lo, start_offset)
+);
@@ -0,0 +1,5 @@
+drop index attempts_idempotency;
+
+alter table attempts drop column payload_hash;
+alter table attempts drop column idempotency_key;
+alter table attempts drop column session_id;
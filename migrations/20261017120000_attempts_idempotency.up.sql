@@ -0,0 +1,5 @@
+alter table attempts add column session_id uuid;
+alter table attempts add column idempotency_key text;
+alter table attempts add column payload_hash blob;
+
+create unique index attempts_idempotency on attempts (session_id, idempotency_key) where idempotency_key is not null;
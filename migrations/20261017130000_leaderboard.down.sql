@@ -0,0 +1,8 @@
+drop index attempts_wins;
+drop index links_player;
+drop index links_session;
+
+alter table attempts drop column theme;
+alter table links drop column player_id;
+alter table attempts drop column player_id;
+alter table links drop column session_id;
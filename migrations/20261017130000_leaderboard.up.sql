@@ -0,0 +1,21 @@
+-- Links are attributed to the session which shared them, so that players
+-- can be ranked on leaderboards under the names they share records with.
+alter table links add column session_id uuid;
+
+update links set session_id = (select session_id from attempts where attempts.id = links.attempt_id);
+
+-- Sessions are short-lived, so players are ranked by the player ID kept by all of their sessions.
+alter table attempts add column player_id uuid;
+alter table links add column player_id uuid;
+
+-- Wins are ranked on the leaderboard of the theme they were played in, which is
+-- taken from the trace on submission. Older attempts take it from their records.
+alter table attempts add column theme text;
+
+update attempts set theme = (
+    select theme from links where links.attempt_id = attempts.id order by created_at limit 1
+);
+
+create index links_session on links (session_id, theme) where session_id is not null;
+create index links_player on links (player_id, created_at) where player_id is not null;
+create index attempts_wins on attempts (gamemode, theme, created_at, player_id) where verification = 'VALID' and kind = 'WIN';
@@ -0,0 +1,14 @@
+-- Visits of shared links, one row per visiting session. Visits without a session
+-- (the first page load of a new player) are counted in a single row per link.
+create table link_visits (
+    code text not null references links on delete cascade,
+    session_id uuid,
+    opens integer not null default 0,
+    -- challenge attempts submitted while playing against the link
+    attempts integer not null default 0,
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+
+-- Null sessions never conflict in unique indexes, so they are indexed as the empty string.
+create unique index link_visits_session on link_visits (code, coalesce(session_id, ''));
@@ -0,0 +1,3 @@
+drop index attempts_challenge;
+
+alter table attempts drop column challenge;
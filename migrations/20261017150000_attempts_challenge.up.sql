@@ -0,0 +1,4 @@
+-- The shared record the attempt was played against.
+alter table attempts add column challenge text references links on delete set null;
+
+create index attempts_challenge on attempts (challenge) where challenge is not null;
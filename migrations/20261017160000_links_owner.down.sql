@@ -0,0 +1,2 @@
+alter table links drop column deleted_at;
+alter table links drop column expires_at;
@@ -0,0 +1,4 @@
+-- Links can be taken down by their owners. Deleted links are kept
+-- so that their codes are not reused and can be reported as gone.
+alter table links add column expires_at timestamp;
+alter table links add column deleted_at timestamp;
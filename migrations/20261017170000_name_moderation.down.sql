@@ -0,0 +1 @@
+drop table name_moderation;
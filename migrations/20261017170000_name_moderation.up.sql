@@ -0,0 +1,11 @@
+-- Names caught by the blocklist, held back from their links until reviewed.
+create table name_moderation (
+    id integer primary key,
+    code text not null references links on delete cascade,
+    name text not null,
+    status text not null default 'PENDING' check (status in ('PENDING', 'APPROVED', 'REJECTED')),
+    created_at timestamp not null,
+    decided_at timestamp
+);
+
+create index name_moderation_pending on name_moderation (created_at) where status = 'PENDING';
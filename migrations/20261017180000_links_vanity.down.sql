@@ -0,0 +1,2 @@
+drop index links_vanity;
+alter table links drop column vanity;
@@ -0,0 +1,5 @@
+-- Vanity codes are chosen by players. Codes which only differ by case
+-- would be mistaken for each other, so vanity codes are unique regardless of case.
+alter table links add column vanity boolean not null default false;
+
+create unique index links_vanity on links (lower(code)) where vanity;
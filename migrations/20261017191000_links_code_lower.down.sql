@@ -0,0 +1 @@
+drop index links_code_lower;
@@ -0,0 +1,2 @@
+-- Vanity codes must differ from all codes, not only from other vanity codes, by more than case.
+create index links_code_lower on links (lower(code));
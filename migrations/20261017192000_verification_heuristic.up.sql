@@ -0,0 +1 @@
+alter table attempts add column verification_heuristic boolean not null default false;
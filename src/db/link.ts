@@ -42,6 +42,8 @@ export function findCachedLink(db: IDBDatabase, info: CodeInfo): Promise<Code |
 
 export type Link = CodeInfo & {
   code: Code
+  /** Lets the creator edit or delete the link. */
+  ownerToken?: string
 }
 
 export function cacheLink(db: IDBDatabase, link: Link): Promise<boolean> {
@@ -204,10 +204,11 @@ class Initial extends State {
     if (end) {
       this.attempt = end.attempt.end(end.kind === 'win')
       this.lastOp = end.lastOp
-      this.rand = end.rand
-    } else {
-      this.rand = props.rand
+      // The server rejects attempts which reuse the random stream, so all
+      // following games, including reset ones, must continue from here.
+      this.props.rand = end.rand
     }
+    this.rand = this.props.rand
     // If the player switched gamemodes mid-play set it now,
     // since the previous game is over.
     if (this.props.nextGamemode) {
@@ -13,6 +13,7 @@ import { insertAttempt, retrieveAttempts } from '$db/attempt.ts'
 import { Redirect } from '$components/Redirect.ts'
 import { defaultGamemode, gamemodes } from './gamemode.ts'
 import { clearSharedRecord, getSharedRecord } from '$share/record.ts'
+import { getCodeFromPath } from '$share/code.ts'
 import MenuAccess from './menu/MenuAccess.svelte'
 import { getRecordDelta } from './menu/record.ts'
 import { getTheme, setTheme, defaultTheme, themes } from './theme.ts'
@@ -24,7 +25,7 @@ import { pause, reset, resume } from '$game/ops.ts'
 import { parse } from 'cookie'
 import type { Animation } from '$game/grid/index.ts'
 import { mount } from 'svelte'
-import { configureSession } from './session.ts'
+import { configureSession, fetchWithBackoff } from './session.ts'
 import { findCachedLink } from '$db/link.ts'
 import { Grid, type GridResizeData } from '$components/Grid.ts'
 import { Tracer, type PointerEvents, type Trace } from '$game/trace.ts'
@@ -162,15 +163,22 @@ const submitAttempt = async (attempt: Attempt, trace: Trace, pointerEvents: Poin
     body.set('rand', JSON.stringify({ exp: sessionRand.exp, signature: sessionRand.signature }))
   }
 
-  const resp = await fetch('/submit', {
-    method: 'POST',
-    body,
-    credentials: 'same-origin',
-  })
-  if (!resp.ok) {
-    throw new Error(resp.statusText, { cause: await resp.text() })
+  // Attempts played against a shared record count towards its stats.
+  const challenge = record && getCodeFromPath(location.pathname)
+  if (challenge) {
+    body.set('challenge', challenge)
   }
 
+  // The key makes retries return the attempt saved by the first request which reached the server.
+  const resp = await fetchWithBackoff(
+    new Request('/submit', {
+      method: 'POST',
+      body,
+      credentials: 'same-origin',
+      headers: { 'Idempotency-Key': crypto.randomUUID() },
+    }),
+  )
+
   const { id }: { id: string } = await resp.json()
 
   return id
@@ -217,6 +225,8 @@ const handleURLAndTitle = async (name: Writable<string | undefined>, duration: n
     return configureTitle(name, title, !!record)
   } else if (status === '404') {
     text = 'The link was not found: is it correct?'
+  } else if (status === '410') {
+    text = 'The link was taken down or has expired.'
   } else {
     text = "The link couldn't be opened, try again later."
   }
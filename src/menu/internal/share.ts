@@ -37,6 +37,7 @@ function makePlural(s: string, count: number): string {
 
 interface Response {
   code: Code
+  ownerToken: string
 }
 
 interface ResponseErrorData {
@@ -134,9 +135,9 @@ export async function getShareContent(
       throw new ResponseError(data)
     }
 
-    const { code: newCode }: Response = await res.json()
+    const { code: newCode, ownerToken }: Response = await res.json()
     code = newCode
-    await cacheLink(db, { ...record, code })
+    await cacheLink(db, { ...record, code, ownerToken })
   }
 
   let text: string
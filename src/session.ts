@@ -175,7 +175,7 @@ async function fetchSession(signal: AbortSignal): Promise<SessionFetch> {
   })
 }
 
-async function fetchWithBackoff(req: Request) {
+export async function fetchWithBackoff(req: Request) {
   let lastErr: unknown
   let res: Response | undefined
 
@@ -185,7 +185,7 @@ async function fetchWithBackoff(req: Request) {
     }
 
     try {
-      res = await fetch(req)
+      res = await fetch(req.clone())
       if (res.ok) {
         return res
       }
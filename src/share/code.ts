@@ -9,6 +9,7 @@ export const getCodeFromPath = (path: string): Code | undefined => {
   return code
 }
 
-export const isCode = (s: string): s is Code => /^[A-Za-z0-9]{6}$/.test(s)
+// Random codes have 6 letters or digits; vanity codes can be longer and contain separators.
+export const isCode = (s: string): s is Code => /^[A-Za-z0-9][A-Za-z0-9_-]{2,22}[A-Za-z0-9]$/.test(s)
 
 export const storageKey = import.meta.env.VITE_RECORD_STORAGE_KEY